@@ -0,0 +1,218 @@
+package pfunc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// PythonBatchTemplate import the function once and invoke it with every payload of the batch, the result of
+// every call is collected with its exception and output, so a failed call does not abort the others. It runs on
+// both python 2 and python 3.
+const PythonBatchTemplate string = `
+import os
+import sys
+import traceback
+import json
+try:
+    from StringIO import StringIO
+except ImportError:
+    from io import StringIO
+` + PythonRuntime + `
+pfunc_channel = pfunc_open_channel(%d, "w")
+pfunc_result_file = pfunc_open_result_file()
+pfunc_install_runtime(*pfunc_callback_channels())
+pfunc_restrict()
+pfunc_extend_sys_path()
+pfunc_configure_encoding()
+try:
+    from %s import %s
+%s
+    pfunc_results = []
+    pfunc_stdout, pfunc_stderr = sys.stdout, sys.stderr
+    for %s in %s:
+        pfunc_output = StringIO()
+        sys.stdout = sys.stderr = pfunc_output
+        try:
+            try:
+                pfunc_result = {"ok": True, "result": pfunc_dumps(%s)}
+            except Exception as e:
+                pfunc_result = {"ok": False, "exception": pfunc_exception(e, sys.exc_info()[2])}
+        finally:
+            sys.stdout, sys.stderr = pfunc_stdout, pfunc_stderr
+        pfunc_result["output"] = pfunc_output.getvalue()
+        pfunc_results.append(pfunc_result)
+    pfunc_send(pfunc_channel, "result", pfunc_dump_result(pfunc_results, pfunc_result_file), '%s', '%s')
+except Exception as e:
+    pfunc_send(pfunc_channel, "exception", json.dumps(pfunc_exception(e, sys.exc_info()[2])), '%s', '%s')
+pfunc_channel.flush()
+`
+
+// batchResult is the result of one call of a batch sent by python, result is the json of return value
+type batchResult struct {
+	Ok        bool         `json:"ok"`
+	Result    string       `json:"result"`
+	Exception *PythonError `json:"exception"`
+	Output    string       `json:"output"`
+}
+
+// InvokeBatch invoke a python function with every params of paramsList by the default runner, see Runner.InvokeBatch
+func InvokeBatch(scriptPath string, funcName string, paramsList [][]interface{}) []PResult {
+	return DefaultRunner().InvokeBatch(scriptPath, funcName, paramsList)
+}
+
+// InvokeBatch invoke a python function with every params of paramsList in one python process, which imports the
+// function once. The result of every call is returned in order, a failed call does not abort the others, and
+// every result fails with the same exception when the process fails. When runner has a pool, the batch is sent to
+// one worker in one request.
+func (r *Runner) InvokeBatch(scriptPath string, funcName string, paramsList [][]interface{}) []PResult {
+	return r.InvokeBatchContext(context.Background(), scriptPath, funcName, paramsList)
+}
+
+// InvokeBatchContext is like InvokeBatch, but the python process is killed when the context is done
+func (r *Runner) InvokeBatchContext(ctx context.Context, scriptPath string, funcName string, paramsList [][]interface{}) []PResult {
+	return r.dispatchBatch(ctx, nil, invocation{scriptPath: scriptPath, funcName: funcName}, paramsList)
+}
+
+// DoBatch is like Do for every args of argsList in one python process, see Runner.InvokeBatch.
+// Return values and errors are in the order of argsList.
+func (w *WrapInfo) DoBatch(argsList [][]interface{}) ([]interface{}, []error) {
+	return w.DoBatchContext(context.Background(), argsList)
+}
+
+// DoBatchContext is like DoBatch, but the python process is killed when the context is done
+func (w *WrapInfo) DoBatchContext(ctx context.Context, argsList [][]interface{}) ([]interface{}, []error) {
+	values := make([]interface{}, len(argsList))
+	errs := make([]error, len(argsList))
+
+	if err := w.ready(); err != nil {
+		for i := range argsList {
+			errs[i] = err
+		}
+		return values, errs
+	}
+
+	paramsList := make([][]interface{}, len(argsList))
+	for i, args := range argsList {
+		paramsList[i] = w.withDefaults(args)
+	}
+
+	runner := w.runner
+	if runner == nil {
+		runner = DefaultRunner()
+	}
+	for i, result := range runner.dispatchBatch(ctx, w.pool, w.invocation(nil), paramsList) {
+		values[i], errs[i] = w.decode(result)
+	}
+	return values, errs
+}
+
+// dispatchBatch invoke every params of paramsList in pool p, or pool of runner when p is nil, or in one new python
+// process when no pool is set
+func (r *Runner) dispatchBatch(ctx context.Context, p *Pool, inv invocation, paramsList [][]interface{}) []PResult {
+	if p == nil {
+		p = r.pool
+	}
+	if p != nil && !r.restricted() {
+		return p.invokeBatch(ctx, inv, paramsList)
+	}
+	return r.doBatch(ctx, inv, paramsList)
+}
+
+// doBatch run every call of batch in a new python process
+func (r *Runner) doBatch(ctx context.Context, inv invocation, paramsList [][]interface{}) []PResult {
+	results := make([]PResult, len(paramsList))
+	if len(paramsList) < 1 {
+		return results
+	}
+
+	result := PResult{}
+	files := r.newPayloadFiles()
+	defer files.close()
+	message, noResult, ok := r.runScript(ctx, inv, &result, files, func(channelFd int) (string, string, error) {
+		return r.generateBatchScript(channelFd, inv, paramsList, files)
+	})
+	fail := func(err error) []PResult {
+		for i := range results {
+			results[i] = result
+			results[i].Exception = err
+		}
+		return results
+	}
+	if !ok {
+		return fail(result.Exception)
+	}
+
+	value, exception := r.parseMessage(message)
+	section, err := files.decode(value)
+	if err != nil {
+		return fail(err)
+	}
+	if len(section) < 1 {
+		if e := exceptionError(exception); len(e.Error()) > 0 {
+			return fail(r.limitError(e))
+		}
+		return fail(r.limitError(noResult))
+	}
+
+	var batch []batchResult
+	if err := json.Unmarshal([]byte(section), &batch); err != nil || len(batch) != len(paramsList) {
+		return fail(fmt.Errorf("invoke python function error: unexpected batch result: %v: %v", err, section))
+	}
+	for i, b := range batch {
+		results[i] = PResult{
+			NoError:            b.Ok,
+			JsonRepresentation: b.Result,
+			TempScript:         result.TempScript,
+			Output:             b.Output,
+			PythonPath:         result.PythonPath,
+		}
+		if b.Ok {
+			results[i].Exception = exceptionError("")
+		} else {
+			results[i].Exception = r.limitError(b.Exception)
+		}
+	}
+	return results
+}
+
+// generateBatchScript generate temp script to invoke function with every params of paramsList
+func (r *Runner) generateBatchScript(channelFd int, inv invocation, paramsList [][]interface{}, files *payloadFiles) (string, string, error) {
+	from, appendPythonPath := inv.importFrom(r.importPaths())
+
+	payloads := make([]payload, len(paramsList))
+	for i, params := range paramsList {
+		call := inv
+		call.params = params
+		payloads[i] = call.payload()
+	}
+	bs, classes, err := marshalClasses(payloads)
+	if err != nil {
+		return "", appendPythonPath, fmt.Errorf("can not serialize params to json value: %v", err)
+	}
+	batchVarName := r.injectVarNamePrefix + "batch"
+	load, err := files.load(bs, classes)
+	if err != nil {
+		return "", appendPythonPath, err
+	}
+	vars := fmt.Sprintf("%s = %s\n", batchVarName, load)
+
+	invoker, err := r.injectScriptFuncInvoke(inv)
+	if err != nil {
+		return "", appendPythonPath, err
+	}
+
+	script := fmt.Sprintf(PythonBatchTemplate,
+		channelFd,
+		from,
+		inv.importName(),
+		TabString(vars, 4),
+		r.payloadVarName(),
+		batchVarName,
+		invoker,
+		r.returnValueStart,
+		r.returnValueEnd,
+		r.exceptionStart,
+		r.exceptionEnd)
+	return script, appendPythonPath, nil
+}
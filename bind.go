@@ -0,0 +1,126 @@
+package pfunc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Bind fill a function variable with an implementation invoking the python function by the default runner,
+// for example:
+//
+//	var divide func(a, b int) (int, error)
+//	err := pfunc.Bind(&divide, "script.py", "divide")
+//
+// Param types and return type are derived from the go function signature. An optional first context.Context
+// param controls cancellation, variadic params are passed as separate python params. The function may return
+// a value, an error, or both with error as the last one. When it does not return an error, a failed invocation
+// panics with the error.
+func Bind(fn interface{}, scriptPath string, funcName string) error {
+	return bind(nil, fn, scriptPath, funcName)
+}
+
+// Bind fill a function variable with an implementation invoking the python function by runner, see Bind
+func (r *Runner) Bind(fn interface{}, scriptPath string, funcName string) error {
+	return bind(r, fn, scriptPath, funcName)
+}
+
+// bind make the function implementation, nil runner means the default runner when the function is called
+func bind(r *Runner, fn interface{}, scriptPath string, funcName string) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Func {
+		return fmt.Errorf("bind target is not a pointer to function variable: %T", fn)
+	}
+	t := v.Elem().Type()
+
+	withContext := t.NumIn() > 0 && t.In(0) == contextType
+
+	var returnType reflect.Type
+	withError := false
+	switch t.NumOut() {
+	case 0:
+	case 1:
+		if t.Out(0) == errorType {
+			withError = true
+		} else {
+			returnType = t.Out(0)
+		}
+	case 2:
+		if t.Out(1) != errorType {
+			return fmt.Errorf("bind target must return error as the last result: %v", t)
+		}
+		if t.Out(0) == errorType {
+			return fmt.Errorf("bind target must return at most one value besides error: %v", t)
+		}
+		returnType = t.Out(0)
+		withError = true
+	default:
+		return fmt.Errorf("bind target must return at most one value besides error: %v", t)
+	}
+
+	impl := func(args []reflect.Value) []reflect.Value {
+		ctx := context.Background()
+		if withContext {
+			if c, ok := args[0].Interface().(context.Context); ok && c != nil {
+				ctx = c
+			}
+			args = args[1:]
+		}
+
+		var params []interface{}
+		for i, arg := range args {
+			if t.IsVariadic() && i == len(args)-1 {
+				for j := 0; j < arg.Len(); j++ {
+					params = append(params, arg.Index(j).Interface())
+				}
+			} else {
+				params = append(params, arg.Interface())
+			}
+		}
+
+		runner := r
+		if runner == nil {
+			runner = DefaultRunner()
+		}
+		result := runner.dispatch(ctx, nil, invocation{scriptPath: scriptPath, funcName: funcName, params: params})
+
+		var err error
+		value := reflect.Value{}
+		if returnType != nil {
+			value = reflect.New(returnType).Elem()
+		}
+		if !result.NoError {
+			err = result.Exception
+		} else if returnType != nil {
+			p := reflect.New(returnType)
+			if e := Unmarshal([]byte(result.JsonRepresentation), p.Interface()); e != nil {
+				err = e
+			} else {
+				value = p.Elem()
+			}
+		}
+
+		if err != nil && !withError {
+			panic(err)
+		}
+
+		var results []reflect.Value
+		if returnType != nil {
+			results = append(results, value)
+		}
+		if withError {
+			e := reflect.New(errorType).Elem()
+			if err != nil {
+				e.Set(reflect.ValueOf(err))
+			}
+			results = append(results, e)
+		}
+		return results
+	}
+
+	v.Elem().Set(reflect.MakeFunc(t, impl))
+	return nil
+}
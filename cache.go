@@ -0,0 +1,285 @@
+package pfunc
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores results of python invocations by key, a cache must be safe to use from many goroutines.
+// Keys are hex strings, values are opaque bytes.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+}
+
+// WithCache cache successful results of invocations of runner, nil disables caching, see WrapInfo.Cache
+func WithCache(c Cache) Option {
+	return func(r *Runner) {
+		r.cache = c
+	}
+}
+
+// Cache cache successful results of the wrapped function, instead of the cache of runner. Results are cached by
+// the content of script, the function name, json of params and the configuration of python process which affects
+// imports, so a cached result is dropped when the script changes, but not when modules imported by the script
+// change. Functions imported from modules are cached by module name. Concurrent invocations of the same key with
+// the same cache run python once and share the result.
+func (w *WrapInfo) Cache(c Cache) *WrapInfo {
+	w.cache = c
+	return w
+}
+
+// cacheEntry is the cached part of a successful result
+type cacheEntry struct {
+	Result string `json:"result"`
+	Output string `json:"output"`
+}
+
+// cacheKey hash python executable, script content or module name, where the script is imported from, function path
+// and params of invocation, and the process configuration of runner which affects imports: working directory,
+// environment variables of python and set by runner, sys.path entries and isolated mode. It fails when the script can not be read or params are not
+// serializable.
+func (r *Runner) cacheKey(inv invocation) (string, error) {
+	h := sha256.New()
+	if len(inv.module) < 1 {
+		content, err := ioutil.ReadFile(inv.scriptPath)
+		if err != nil {
+			return "", err
+		}
+		h.Write(content)
+	}
+	process := r.processConfig(r.Environ())
+	process.WorkDir, _ = filepath.Abs(process.WorkDir)
+	process.Env = removeEnv(process.Env, func(key string) bool {
+		_, set := r.envVars[key]
+		return !strings.HasPrefix(key, "PYTHON") && !set
+	})
+	sort.Strings(process.Env)
+	from, path := inv.importFrom(r.importPaths())
+	bs, err := Marshal(struct {
+		Executable string        `json:"executable"`
+		Module     string        `json:"module"`
+		Import     []string      `json:"import"`
+		Func       string        `json:"func"`
+		Method     string        `json:"method"`
+		Payload    payload       `json:"payload"`
+		Encoding   Encoding      `json:"encoding"`
+		Process    ProcessConfig `json:"process"`
+	}{r.executable, inv.module, []string{from, path}, inv.funcName, inv.method, inv.payload(), r.encoding, process})
+	if err != nil {
+		return "", err
+	}
+	h.Write(bs)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cached return the cached result of invocation, or invoke it by invoke and cache the result when it succeeded
+// and the script did not change while it ran, since python may have run another content than the key was made
+// of. Concurrent calls of the same key wait for the first one.
+func (r *Runner) cached(ctx context.Context, c Cache, inv invocation, invoke func() PResult) PResult {
+	key, err := r.cacheKey(inv)
+	if err != nil {
+		return invoke()
+	}
+	if result, ok := cachedResult(c, key); ok {
+		return result
+	}
+
+	result, shared := flights.do(c, key, func() PResult {
+		if result, ok := cachedResult(c, key); ok {
+			return result
+		}
+		result := invoke()
+		if after, err := r.cacheKey(inv); result.NoError && err == nil && after == key {
+			if bs, err := json.Marshal(cacheEntry{Result: result.JsonRepresentation, Output: result.Output}); err == nil {
+				c.Set(key, bs)
+			}
+		}
+		return result
+	})
+	// the first call was killed by its own context, invoke again with this one
+	if shared && (result.Exception == ErrCanceled || result.Exception == ErrTimeout) && ctx.Err() == nil {
+		return r.cached(ctx, c, inv, invoke)
+	}
+	return result
+}
+
+// cachedResult decode result cached by key
+func cachedResult(c Cache, key string) (PResult, bool) {
+	bs, ok := c.Get(key)
+	if !ok {
+		return PResult{}, false
+	}
+	entry := cacheEntry{}
+	if err := json.Unmarshal(bs, &entry); err != nil {
+		return PResult{}, false
+	}
+	return PResult{NoError: true, JsonRepresentation: entry.Result, Output: entry.Output, Exception: errors.New("")}, true
+}
+
+// flight is an invocation running for callers of the same key
+type flight struct {
+	done   chan struct{}
+	result PResult
+}
+
+// flightKey is a key of a cache
+type flightKey struct {
+	cache Cache
+	key   string
+}
+
+// flightGroup collapse concurrent invocations of the same key of the same cache
+type flightGroup struct {
+	lock    sync.Mutex
+	flights map[flightKey]*flight
+}
+
+var flights = &flightGroup{flights: map[flightKey]*flight{}}
+
+// do run fn once for concurrent callers of key of cache c, shared tells the result was made for another caller.
+// Callers are not collapsed when c can not be compared.
+func (g *flightGroup) do(c Cache, key string, fn func() PResult) (PResult, bool) {
+	if !reflect.TypeOf(c).Comparable() {
+		return fn(), false
+	}
+	k := flightKey{cache: c, key: key}
+	g.lock.Lock()
+	if f, ok := g.flights[k]; ok {
+		g.lock.Unlock()
+		<-f.done
+		return f.result, true
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[k] = f
+	g.lock.Unlock()
+
+	defer func() {
+		g.lock.Lock()
+		delete(g.flights, k)
+		g.lock.Unlock()
+		close(f.done)
+	}()
+	f.result = fn()
+	return f.result, false
+}
+
+// memoryCache is a least recently used cache in memory
+type memoryCache struct {
+	lock    sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// memoryEntry is an element of order of memoryCache
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache make an in-memory cache keeping at most size least recently used results, for at most ttl.
+// Size less than 1 means no limit, ttl 0 means results never expire.
+func NewMemoryCache(size int, ttl time.Duration) Cache {
+	return &memoryCache{size: size, ttl: ttl, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+func (c *memoryCache) Get(key string) ([]byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true
+}
+
+func (c *memoryCache) Set(key string, value []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry := &memoryEntry{key: key, value: value}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.size > 0 && c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*memoryEntry).key)
+	}
+}
+
+// diskCache stores every result in a file of a directory
+type diskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewDiskCache make a persistent cache storing results in files of dir, which is created when missing.
+// Results older than ttl are ignored, ttl 0 means results never expire.
+func NewDiskCache(dir string, ttl time.Duration) (Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &diskCache{dir: dir, ttl: ttl}, nil
+}
+
+func (c *diskCache) Get(key string) ([]byte, bool) {
+	path := filepath.Join(c.dir, key)
+	if c.ttl > 0 {
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) > c.ttl {
+			return nil, false
+		}
+	}
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return bs, true
+}
+
+// Set write value to a temp file and rename it, so readers never see a partial file
+func (c *diskCache) Set(key string, value []byte) {
+	f, err := ioutil.TempFile(c.dir, ".pfunc_cache_")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(value)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), filepath.Join(c.dir, key)); err != nil {
+		os.Remove(f.Name())
+	}
+}
@@ -0,0 +1,298 @@
+package pfunc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// environment variable telling temp script the file descriptors of callback channels
+const callbackFdsEnv = "PFUNC_CALLBACK_FDS"
+
+// pythonCallbackRuntime is the part of PythonRuntime opening callback channels and installing the pfunc module calling go
+const pythonCallbackRuntime string = `
+
+def pfunc_callback_channels():
+    fds = os.environ.pop("PFUNC_CALLBACK_FDS", "")
+    if not fds:
+        return None, None
+    fd_out, fd_in = [int(fd) for fd in fds.split(",")]
+    return pfunc_open_channel(fd_out, "w"), pfunc_open_channel(fd_in, "r")
+
+
+def pfunc_install_runtime(channel_out, channel_in):
+    import threading
+    import types
+
+    class CallbackError(Exception):
+        __module__ = "pfunc_runtime"
+
+    lock = threading.Lock()
+
+    def call(name, *args):
+        if channel_out is None:
+            raise CallbackError("no go callback is registered")
+        lock.acquire()
+        try:
+            channel_out.write(pfunc_dumps({"callback": name, "args": args}) + "\n")
+            channel_out.flush()
+            line = channel_in.readline()
+        finally:
+            lock.release()
+        if not line:
+            raise CallbackError("go callback channel is closed")
+        response = json.loads(line)
+        if not response.get("ok"):
+            raise CallbackError(response.get("error"))
+        return pfunc_revive(response.get("result"), response.get("classes", []))
+
+    module = types.ModuleType("pfunc_runtime")
+    module.call = call
+    module.CallbackError = CallbackError
+    # writes to the channel are serialized with output of pool workers
+    module._lock = lock
+    sys.modules["pfunc_runtime"] = module
+    return module
+`
+
+// callbacks holds go functions callable from python, it is shared by a runner, its copies made by With
+// and pools started by them
+type callbacks struct {
+	lock  sync.RWMutex
+	funcs map[string]reflect.Value
+}
+
+// callbackRequest is the json line python sends to call a go function
+type callbackRequest struct {
+	Callback string            `json:"callback"`
+	Args     []json.RawMessage `json:"args"`
+}
+
+// callbackResponse is the json line sent back to python
+type callbackResponse struct {
+	Ok     bool        `json:"ok"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	// python classes of objects in result
+	Classes []string `json:"classes,omitempty"`
+}
+
+// RegisterCallback make a go function callable from python code invoked by the default runner, see Runner.RegisterCallback
+func RegisterCallback(name string, fn interface{}) error {
+	return DefaultRunner().RegisterCallback(name, fn)
+}
+
+// RegisterCallback make a go function callable from python code invoked by runner, for example:
+//
+//	r.RegisterCallback("lookup_user", func(ctx context.Context, id int) (User, error) { ... })
+//
+// and in python:
+//
+//	import pfunc_runtime
+//	user = pfunc_runtime.call("lookup_user", 42)
+//
+// Params are decoded from json of python arguments, an optional first context.Context param gets the context
+// of the invocation. The function may return a value, an error, or both with error as the last one, a returned
+// error or a panic is raised in python as pfunc_runtime.CallbackError. Callbacks are shared by copies of runner
+// made by With and by its pools. Calls from python are served one at a time per python process.
+func (r *Runner) RegisterCallback(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("callback is not a function: %T", fn)
+	}
+	t := v.Type()
+	switch {
+	case t.NumOut() > 2:
+		return fmt.Errorf("callback must return at most one value besides error: %v", t)
+	case t.NumOut() == 2 && (t.Out(1) != errorType || t.Out(0) == errorType):
+		return fmt.Errorf("callback must return at most one value besides error: %v", t)
+	}
+
+	r.callbacks.lock.Lock()
+	defer r.callbacks.lock.Unlock()
+	r.callbacks.funcs[name] = v
+	return nil
+}
+
+func newCallbacks() *callbacks {
+	return &callbacks{funcs: map[string]reflect.Value{}}
+}
+
+// empty tells if no callback is registered
+func (c *callbacks) empty() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return len(c.funcs) < 1
+}
+
+// call run the requested go function and describe its result or error
+func (c *callbacks) call(ctx context.Context, request callbackRequest) (response callbackResponse) {
+	c.lock.RLock()
+	fn, ok := c.funcs[request.Callback]
+	c.lock.RUnlock()
+	if !ok {
+		return callbackResponse{Error: fmt.Sprintf("go callback is not registered: %v", request.Callback)}
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			response = callbackResponse{Error: fmt.Sprintf("go callback %v panic: %v", request.Callback, e)}
+		}
+	}()
+
+	t := fn.Type()
+	var args []reflect.Value
+	if t.NumIn() > 0 && t.In(0) == contextType {
+		args = append(args, reflect.ValueOf(ctx))
+	}
+	fixed := t.NumIn() - len(args)
+	if t.IsVariadic() {
+		fixed--
+	}
+	if len(request.Args) < fixed || (!t.IsVariadic() && len(request.Args) > fixed) {
+		return callbackResponse{Error: fmt.Sprintf("go callback %v takes %v arguments, %v given", request.Callback, fixed, len(request.Args))}
+	}
+	for i, raw := range request.Args {
+		var at reflect.Type
+		if i < fixed {
+			at = t.In(len(args))
+		} else {
+			at = t.In(t.NumIn() - 1).Elem()
+		}
+		p := reflect.New(at)
+		if err := Unmarshal(raw, p.Interface()); err != nil {
+			return callbackResponse{Error: fmt.Sprintf("go callback %v argument %v error: %v", request.Callback, i, err)}
+		}
+		args = append(args, p.Elem())
+	}
+
+	results := fn.Call(args)
+	if n := len(results); n > 0 && t.Out(n-1) == errorType {
+		if err, _ := results[n-1].Interface().(error); err != nil {
+			return callbackResponse{Error: err.Error()}
+		}
+		results = results[:n-1]
+	}
+	response.Ok = true
+	if len(results) > 0 {
+		response.Result = results[0].Interface()
+	}
+	return response
+}
+
+// reply call go function of one request line and encode the response line
+func (c *callbacks) reply(ctx context.Context, request callbackRequest) []byte {
+	response := c.call(ctx, request)
+	result, classes, err := marshalClasses(response.Result)
+	var bs []byte
+	if err == nil {
+		response.Result, response.Classes = json.RawMessage(result), classes
+		bs, err = json.Marshal(response)
+	}
+	if err != nil {
+		bs, _ = json.Marshal(callbackResponse{Error: fmt.Sprintf("go callback %v result error: %v", request.Callback, err)})
+	}
+	return append(bs, '\n')
+}
+
+// serve answer callback requests read from python until EOF
+func (c *callbacks) serve(ctx context.Context, requests io.Reader, responses io.Writer) {
+	reader := bufio.NewReader(requests)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		request := callbackRequest{}
+		var response []byte
+		if err := json.Unmarshal(line, &request); err != nil {
+			response, _ = json.Marshal(callbackResponse{Error: fmt.Sprintf("unexpected callback request: %v", err)})
+			response = append(response, '\n')
+		} else {
+			response = c.reply(ctx, request)
+		}
+		if _, err := responses.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+// callbackChannel is a pair of pipes through which a python process started for one invocation calls go callbacks
+type callbackChannel struct {
+	callbacks *callbacks
+	requests  *os.File
+	responses *os.File
+	child     []*os.File
+	done      chan struct{}
+}
+
+// openCallbackChannel pass a pair of pipes to command when callbacks are registered,
+// it returns nil if there is no callback or extra file descriptors are not supported
+func openCallbackChannel(cmd *exec.Cmd, c *callbacks) (*callbackChannel, error) {
+	if !resultChannelSupported || c == nil || c.empty() {
+		return nil, nil
+	}
+
+	requestReader, requestWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	responseReader, responseWriter, err := os.Pipe()
+	if err != nil {
+		requestReader.Close()
+		requestWriter.Close()
+		return nil, err
+	}
+
+	fd := extraFilesFd + len(cmd.ExtraFiles)
+	cmd.ExtraFiles = append(cmd.ExtraFiles, requestWriter, responseReader)
+	cmd.Env = append(cmd.Env, callbackFdsEnv+"="+strconv.Itoa(fd)+","+strconv.Itoa(fd+1))
+	return &callbackChannel{
+		callbacks: c,
+		requests:  requestReader,
+		responses: responseWriter,
+		child:     []*os.File{requestWriter, responseReader},
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// started close ends of pipes passed to python and serve its callback requests until it exits
+func (cc *callbackChannel) started(ctx context.Context) {
+	if cc == nil {
+		return
+	}
+	for _, f := range cc.child {
+		f.Close()
+	}
+	go func() {
+		cc.callbacks.serve(ctx, cc.requests, cc.responses)
+		cc.requests.Close()
+		cc.responses.Close()
+		close(cc.done)
+	}()
+}
+
+// wait until python exited and the last callback request is served
+func (cc *callbackChannel) wait() {
+	if cc == nil {
+		return
+	}
+	<-cc.done
+}
+
+// close release all ends of pipes when the command is not started
+func (cc *callbackChannel) close() {
+	if cc == nil {
+		return
+	}
+	for _, f := range append(cc.child, cc.requests, cc.responses) {
+		f.Close()
+	}
+}
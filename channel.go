@@ -0,0 +1,170 @@
+package pfunc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// resultChannelSupported tells if python can send results through an extra file descriptor,
+// exec.Cmd.ExtraFiles is not supported on windows, where results are written to stdout between markers
+var resultChannelSupported = runtime.GOOS != "windows"
+
+// file descriptor of the first entry of exec.Cmd.ExtraFiles in child process
+const extraFilesFd = 3
+
+// pythonChannelRuntime is the part of PythonRuntime opening the result channel and sending messages through it
+const pythonChannelRuntime string = `
+
+def pfunc_open_channel(fd, mode):
+    if fd < 0:
+        return sys.stdin if mode == "r" else sys.stdout
+    try:
+        import fcntl
+        fcntl.fcntl(fd, fcntl.F_SETFD, fcntl.fcntl(fd, fcntl.F_GETFD) | fcntl.FD_CLOEXEC)
+    except ImportError:
+        pass
+    return os.fdopen(fd, mode)
+
+
+# send json data as one envelope through the result channel, or between markers when it is stdout
+def pfunc_send(channel, key, data, start, end):
+    if channel is sys.stdout:
+        channel.write(start + data + end)
+    elif data == "@pfunc_result_file":
+        channel.write('{"result_file": true}')
+    else:
+        channel.write('{"' + key + '": ' + data + '}')
+
+
+# send json data of a stream as one envelope per line, or between markers on a line when channel is stdout
+def pfunc_send_line(channel, key, data, start, end):
+    if channel is sys.stdout:
+        channel.write("\n" + start + data + end + "\n")
+    else:
+        channel.write('{"' + key + '": ' + (data or "true") + '}\n')
+    channel.flush()
+`
+
+// resultChannel is a pipe through which python sends return value or exception of an invocation,
+// so stdout and stderr are left to user code
+type resultChannel struct {
+	fd       int
+	reader   *os.File
+	writer   *os.File
+	messages chan string
+}
+
+// openResultChannel pass write end of a new pipe to command, fd of channel is -1 if it is not supported
+func openResultChannel(cmd *exec.Cmd) (*resultChannel, error) {
+	c := &resultChannel{fd: -1}
+	if !resultChannelSupported {
+		return c, nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	c.fd = extraFilesFd + len(cmd.ExtraFiles)
+	c.reader = reader
+	c.writer = writer
+	cmd.ExtraFiles = append(cmd.ExtraFiles, writer)
+	return c, nil
+}
+
+// started close write end of pipe in current process and start reading message from python
+func (c *resultChannel) started() {
+	if c.fd < 0 {
+		return
+	}
+	c.writer.Close()
+	c.messages = make(chan string, 1)
+	go func() {
+		bs, _ := ioutil.ReadAll(c.reader)
+		c.reader.Close()
+		c.messages <- string(bs)
+	}()
+}
+
+// close release both ends of pipe when the command is not started
+func (c *resultChannel) close() {
+	if c.fd < 0 {
+		return
+	}
+	c.writer.Close()
+	c.reader.Close()
+}
+
+// message return what python sent after it exited, which is stdout when channel is not supported
+func (c *resultChannel) message(stdout string) string {
+	if c.fd < 0 {
+		return stdout
+	}
+	return <-c.messages
+}
+
+// streamReader return reader of messages sent by python while it is running, which is stdout when channel is
+// not supported, it must be called before the command starts
+func (c *resultChannel) streamReader(cmd *exec.Cmd) (io.ReadCloser, error) {
+	if c.fd < 0 {
+		return cmd.StdoutPipe()
+	}
+	return c.reader, nil
+}
+
+// streamStarted close write end of pipe in current process, so reader gets EOF when python exits
+func (c *resultChannel) streamStarted() {
+	if c.fd < 0 {
+		return
+	}
+	c.writer.Close()
+}
+
+// resultEnvelope is the message python sends through the result channel, holding the json return value, or
+// telling it is written to the result file, or the json exception
+type resultEnvelope struct {
+	Result     json.RawMessage `json:"result"`
+	ResultFile bool            `json:"result_file"`
+	Exception  json.RawMessage `json:"exception"`
+}
+
+// parseMessage return the json return value and the json exception in message python sent, which is one envelope
+// through the result channel, or sections between markers when it is stdout. Both are empty when python sent
+// nothing.
+func (r *Runner) parseMessage(message string) (string, string) {
+	if !resultChannelSupported {
+		return lastSectionBetween(message, r.returnValueStart, r.returnValueEnd),
+			lastSectionBetween(message, r.exceptionStart, r.exceptionEnd)
+	}
+	if len(message) < 1 {
+		return "", ""
+	}
+	var e resultEnvelope
+	if err := json.Unmarshal([]byte(message), &e); err != nil {
+		return "", fmt.Sprintf("invoke python function error: unexpected result message: %v: %v", err, message)
+	}
+	if e.ResultFile {
+		return resultFileMarker, string(e.Exception)
+	}
+	return string(e.Result), string(e.Exception)
+}
+
+// lastSectionBetween return substring between the first prefix and the last suffix after it, so a suffix in the
+// section does not end it
+func lastSectionBetween(str string, prefix string, suffix string) string {
+	start := strings.Index(str, prefix)
+	if start < 0 {
+		return ""
+	}
+	end := strings.LastIndex(str, suffix)
+	if end < start+len(prefix) {
+		return ""
+	}
+	return str[start+len(prefix) : end]
+}
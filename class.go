@@ -0,0 +1,108 @@
+package pfunc
+
+import (
+	"context"
+	"fmt"
+)
+
+// ClassInfo describe a python class and the params to construct its instance, every method invocation
+// constructs a new instance and invokes the method on it in one invocation, for example:
+//
+//	result := pfunc.Class("model.py", "Model").New("resnet").NewKeyword("weight", 2).Call("predict", 3)
+//
+// Class names and method names may be dotted attribute paths like "outer.Inner".
+type ClassInfo struct {
+	module     string
+	scriptPath string
+	className  string
+	params     []interface{}
+	keywords   map[string]interface{}
+	runner     *Runner
+	pool       *Pool
+}
+
+// Class describe a python class invoked by the default runner
+func Class(scriptPath string, className string) *ClassInfo {
+	return &ClassInfo{scriptPath: scriptPath, className: className}
+}
+
+// Class describe a python class invoked by runner
+func (r *Runner) Class(scriptPath string, className string) *ClassInfo {
+	c := Class(scriptPath, className)
+	c.runner = r
+	return c
+}
+
+// New set the positional params of constructor
+func (c *ClassInfo) New(params ...interface{}) *ClassInfo {
+	c.params = params
+	return c
+}
+
+// NewKeyword set a keyword param of constructor
+func (c *ClassInfo) NewKeyword(keyword string, value interface{}) *ClassInfo {
+	if c.keywords == nil {
+		c.keywords = map[string]interface{}{}
+	}
+	c.keywords[keyword] = value
+	return c
+}
+
+// Pool dispatch invocations to workers of the pool instead of the pool of runner
+func (c *ClassInfo) Pool(p *Pool) *ClassInfo {
+	c.pool = p
+	return c
+}
+
+// With make the class invoked by a copy of its runner with options applied, see WrapInfo.With
+func (c *ClassInfo) With(options ...Option) *ClassInfo {
+	c.runner = c.runnerOrDefault().With(options...)
+	return c
+}
+
+// Method wrap a method of a new instance as go function, see Func
+func (c *ClassInfo) Method(name string) *WrapInfo {
+	w := Func(c.scriptPath, c.className)
+	w.module = c.module
+	w.runner = c.runner
+	w.pool = c.pool
+	w.method = name
+	w.initParams = append([]interface{}(nil), c.params...)
+	if c.keywords != nil {
+		w.initKw = map[string]interface{}{}
+		for k, v := range c.keywords {
+			w.initKw[k] = v
+		}
+	}
+	return w
+}
+
+// Call invoke a method of a new instance
+func (c *ClassInfo) Call(method string, params ...interface{}) PResult {
+	return c.CallContext(context.Background(), method, params...)
+}
+
+// CallContext is like Call, but the python process is killed when the context is done
+func (c *ClassInfo) CallContext(ctx context.Context, method string, params ...interface{}) PResult {
+	return c.runnerOrDefault().dispatch(ctx, c.pool, c.Method(method).invocation(params))
+}
+
+// Object construct an instance in a pool worker and keep it there, the pool set by Pool or the pool of runner is used
+func (c *ClassInfo) Object(ctx context.Context) (*PyObject, error) {
+	p := c.pool
+	if p == nil {
+		p = c.runnerOrDefault().pool
+	}
+	if p == nil {
+		return nil, fmt.Errorf("invoke python function error: no pool to keep python object")
+	}
+	result, reply := p.invokeFunc(ctx, invocation{module: c.module, scriptPath: c.scriptPath, funcName: c.className, params: c.params, kw: c.keywords}, true)
+	return newPyObject(result, reply)
+}
+
+func (c *ClassInfo) runnerOrDefault() *Runner {
+	if c.runner == nil {
+		return DefaultRunner()
+	}
+	return c.runner
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// config of one generated file
+type config struct {
+	scriptPath  string
+	runtimePath string
+	pkg         string
+	executable  string
+	funcs       []string
+}
+
+// goParam is one param of generated go function
+type goParam struct {
+	Name   string
+	Type   string
+	PyName string
+}
+
+// goOption is one field of options struct, for a python param with default value. A positional only param is
+// passed positionally, at index of params, so the option of previous positional only param must be set too.
+type goOption struct {
+	Field      string
+	Type       string
+	PyName     string
+	Default    string
+	Positional bool
+	Index      int
+	Previous   string
+}
+
+type goFunction struct {
+	Name       string
+	PyName     string
+	Signature  string
+	Doc        []string
+	Positional []goParam
+	Keyword    []goParam
+	Options    []goOption
+	OptionsTyp string
+	Kwargs     string
+	Varargs    *goParam
+	Returns    string
+}
+
+type goField struct {
+	Name string
+	Type string
+	Tag  string
+}
+
+type goStruct struct {
+	Name   string
+	Kind   string
+	Doc    []string
+	Fields []goField
+}
+
+type goFile struct {
+	Script      string
+	Package     string
+	RuntimePath string
+	Prefix      string
+	Structs     []goStruct
+	Functions   []goFunction
+	// some options are positional only params, whose check needs errors package
+	Positional bool
+}
+
+// identifiers used by generated function bodies, params with these names are renamed
+var reservedNames = map[string]bool{
+	"ctx": true, "options": true, "result": true, "params": true, "kwargs": true, "err": true, "k": true, "v": true,
+}
+
+const fileTemplate = `// Code generated by pfuncgen from {{.Script}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"{{if .Positional}}
+	"errors"{{end}}
+
+	"github.com/gitpillow/pfunc"
+)
+
+// {{.Prefix}}Script is the path of python script {{.Script}} used at runtime
+var {{.Prefix}}Script = {{printf "%q" .RuntimePath}}
+
+// {{.Prefix}}Runner invokes python functions of {{.Script}}, nil means the default runner
+var {{.Prefix}}Runner *pfunc.Runner
+{{range .Structs}}
+// {{.Name}} is python {{.Kind}} {{.Name}}.{{range .Doc}}
+//{{if .}} {{.}}{{end}}{{end}}
+type {{.Name}} struct { {{range .Fields}}
+	{{.Name}} {{.Type}} ` + "`" + `{{.Tag}}` + "`" + `{{end}}
+}
+{{end}}{{range .Functions}}{{$f := .}}{{if .Options}}
+// {{.OptionsTyp}} holds optional params of python function {{.PyName}}, nil fields are not passed.
+type {{.OptionsTyp}} struct { {{range .Options}}
+	// {{.Field}} defaults to {{.Default}} in python
+	{{.Field}} *{{.Type}}{{end}}
+}
+{{end}}
+// {{.Name}} invokes python function {{.Signature}}.{{range .Doc}}
+//{{if .}} {{.}}{{end}}{{end}}
+func {{.Name}}(ctx context.Context{{range .Positional}}, {{.Name}} {{.Type}}{{end}}{{range .Keyword}}, {{.Name}} {{.Type}}{{end}}{{if .Options}}, options *{{.OptionsTyp}}{{end}}{{if .Kwargs}}, {{.Kwargs}} map[string]interface{}{{end}}{{with .Varargs}}, {{.Name}} ...{{.Type}}{{end}}) ({{with .Returns}}{{.}}, {{end}}error) {
+{{- if .Returns}}
+	var result {{.Returns}}{{end}}
+	params := []interface{}{ {{range $i, $p := .Positional}}{{if $i}}, {{end}}{{$p.Name}}{{end}} }{{with .Varargs}}
+	for _, v := range {{.Name}} {
+		params = append(params, v)
+	}{{end}}
+	kwargs := map[string]interface{}{ {{range .Keyword}}
+		{{printf "%q" .PyName}}: {{.Name}},{{end}}
+	}{{if .Options}}
+	if options != nil { {{range .Options}}
+		if options.{{.Field}} != nil { {{if .Positional}}{{if .Previous}}
+			if len(params) != {{.Index}} {
+				return {{if $f.Returns}}result, {{end}}errors.New("{{$f.OptionsTyp}}.{{.Previous}} must be set with {{.Field}}, positional only params are passed in order")
+			}{{end}}
+			params = append(params, *options.{{.Field}}){{else}}
+			kwargs[{{printf "%q" .PyName}}] = *options.{{.Field}}{{end}}
+		}{{end}}
+	}{{end}}{{if .Kwargs}}
+	for k, v := range {{.Kwargs}} {
+		kwargs[k] = v
+	}{{end}}
+{{if .Returns}}
+	err := {{$.Prefix | lower}}Call(ctx, {{printf "%q" .PyName}}, &result, params, kwargs)
+	return result, err
+{{else}}
+	return {{$.Prefix | lower}}Call(ctx, {{printf "%q" .PyName}}, nil, params, kwargs)
+{{end}} }
+{{end}}
+// {{.Prefix | lower}}Call invoke python function and decode its return value into result
+func {{.Prefix | lower}}Call(ctx context.Context, funcName string, result interface{}, params []interface{}, kwargs map[string]interface{}) error {
+	w := pfunc.Func({{.Prefix}}Script, funcName)
+	if {{.Prefix}}Runner != nil {
+		w = {{.Prefix}}Runner.Func({{.Prefix}}Script, funcName)
+	}
+	w.Params(params...)
+	for k, v := range kwargs {
+		w.KeyWrodParam(k, v)
+	}
+	raw, err := w.Return(json.RawMessage{}).DoContext(ctx)
+	if err != nil || result == nil {
+		return err
+	}
+	return pfunc.Unmarshal(raw.(json.RawMessage), result)
+}
+`
+
+var fileTmpl = template.Must(template.New("file").Funcs(template.FuncMap{
+	"lower": lowerFirst,
+}).Parse(fileTemplate))
+
+// generate describe the python script and render go wrappers of it
+func generate(c config) ([]byte, error) {
+	script, err := inspect(c.executable, c.scriptPath)
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Base(c.scriptPath)
+	f := goFile{
+		Script:      base,
+		Package:     c.pkg,
+		RuntimePath: filepath.ToSlash(c.runtimePath),
+		Prefix:      exported(strings.TrimSuffix(base, filepath.Ext(base))),
+	}
+
+	records := map[string]bool{}
+	for _, r := range script.Records {
+		records[r.Name] = true
+	}
+	names := map[string]string{f.Prefix + "Script": "script path", f.Prefix + "Runner": "runner"}
+	declare := func(name string, what string) error {
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("generated %v conflicts with %v: %v", what, other, name)
+		}
+		names[name] = what
+		return nil
+	}
+
+	for _, r := range script.Records {
+		s := goStruct{Name: exported(r.Name), Kind: r.Kind, Doc: docLines(r.Doc)}
+		if err := declare(s.Name, "struct of "+r.Name); err != nil {
+			return nil, err
+		}
+		for _, field := range r.Fields {
+			// fields with defaults are nullable, so nil leaves the default to python and zero values are still sent
+			tag := `json:"` + field.Name
+			typ := goType(field.Type, records)
+			if field.Optional {
+				tag += ",omitempty"
+				typ = optional(typ)
+			}
+			s.Fields = append(s.Fields, goField{Name: exported(field.Name), Type: typ, Tag: tag + `"`})
+		}
+		f.Structs = append(f.Structs, s)
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range c.funcs {
+		wanted[name] = true
+	}
+	for _, pf := range script.Functions {
+		if len(wanted) > 0 && !wanted[pf.Name] {
+			continue
+		}
+		gf := wrapFunction(pf, records)
+		for _, o := range gf.Options {
+			f.Positional = f.Positional || o.Positional
+		}
+		if err := declare(gf.Name, "function of "+pf.Name); err != nil {
+			return nil, err
+		}
+		if len(gf.Options) > 0 {
+			if err := declare(gf.OptionsTyp, "options of "+pf.Name); err != nil {
+				return nil, err
+			}
+		}
+		f.Functions = append(f.Functions, gf)
+	}
+
+	buffer := bytes.Buffer{}
+	if err := fileTmpl.Execute(&buffer, f); err != nil {
+		return nil, err
+	}
+	bs, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code error: %v\n%s", err, buffer.String())
+	}
+	return bs, nil
+}
+
+// wrapFunction map python function to go wrapper function
+func wrapFunction(pf *pyFunction, records map[string]bool) goFunction {
+	gf := goFunction{
+		Name:       exported(pf.Name),
+		PyName:     pf.Name,
+		Doc:        docLines(pf.Doc),
+		OptionsTyp: exported(pf.Name) + "Options",
+	}
+
+	hasVarargs := false
+	for _, p := range pf.Params {
+		if p.Kind == "varargs" {
+			hasVarargs = true
+		}
+	}
+
+	var signature []string
+	previous := ""
+	for i, p := range pf.Params {
+		name := unexported(p.Name)
+		typ := goType(p.Type, records)
+		s := p.Name
+		switch p.Kind {
+		case "varargs":
+			s = "*" + s
+			gf.Varargs = &goParam{Name: name, Type: typ, PyName: p.Name}
+		case "kwargs":
+			s = "**" + s
+			gf.Kwargs = name
+		case "positional", "positional_only":
+			// positional params before varargs must be filled positionally
+			if p.Default == nil || hasVarargs {
+				gf.Positional = append(gf.Positional, goParam{Name: name, Type: typ, PyName: p.Name})
+			} else if p.Kind == "positional_only" {
+				gf.Options = append(gf.Options, goOption{Field: exported(p.Name), Type: typ, PyName: p.Name, Default: *p.Default,
+					Positional: true, Index: i, Previous: previous})
+				previous = exported(p.Name)
+			} else {
+				gf.Options = append(gf.Options, goOption{Field: exported(p.Name), Type: typ, PyName: p.Name, Default: *p.Default})
+			}
+		case "keyword":
+			if p.Default == nil {
+				gf.Keyword = append(gf.Keyword, goParam{Name: name, Type: typ, PyName: p.Name})
+			} else {
+				gf.Options = append(gf.Options, goOption{Field: exported(p.Name), Type: typ, PyName: p.Name, Default: *p.Default})
+			}
+		}
+		if p.Default != nil {
+			s += "=" + *p.Default
+		}
+		signature = append(signature, s)
+		if p.Kind == "positional_only" && (i+1 == len(pf.Params) || pf.Params[i+1].Kind != "positional_only") {
+			signature = append(signature, "/")
+		}
+	}
+	gf.Signature = pf.Name + "(" + strings.Join(signature, ", ") + ")"
+
+	if pf.Returns == nil {
+		gf.Returns = "interface{}"
+	} else if pf.Returns.Name != "None" {
+		gf.Returns = goType(pf.Returns, records)
+	}
+	return gf
+}
+
+// goType map python type annotation to go type, unknown types are interface{}
+func goType(t *pyType, records map[string]bool) string {
+	if t == nil {
+		return "interface{}"
+	}
+	arg := func(i int) *pyType {
+		if i < len(t.Args) {
+			return t.Args[i]
+		}
+		return nil
+	}
+
+	switch t.Name {
+	case "int":
+		return "int"
+	case "float":
+		return "float64"
+	case "str":
+		return "string"
+	case "bool":
+		return "bool"
+	case "List", "list", "Sequence", "MutableSequence", "Iterable", "Collection", "Set", "set", "FrozenSet", "frozenset":
+		return "[]" + goType(arg(0), records)
+	case "Tuple", "tuple":
+		if len(t.Args) == 2 && t.Args[1] != nil && t.Args[1].Name == "..." {
+			return "[]" + goType(arg(0), records)
+		}
+		return "[]interface{}"
+	case "Dict", "dict", "Mapping", "MutableMapping":
+		return "map[string]" + goType(arg(1), records)
+	case "Optional":
+		return optional(goType(arg(0), records))
+	case "Union":
+		var others []*pyType
+		for _, a := range t.Args {
+			if a != nil && a.Name != "None" {
+				others = append(others, a)
+			}
+		}
+		if len(others) != 1 {
+			return "interface{}"
+		}
+		if len(others) < len(t.Args) {
+			return optional(goType(others[0], records))
+		}
+		return goType(others[0], records)
+	}
+	if records[t.Name] {
+		return exported(t.Name)
+	}
+	return "interface{}"
+}
+
+// optional make go type nullable
+func optional(typ string) string {
+	if strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "interface{}" {
+		return typ
+	}
+	return "*" + typ
+}
+
+// exported convert python name like snake_case to exported go name like SnakeCase, names whose first letter has
+// no upper case, like non latin letters, get a X prefix
+func exported(name string) string {
+	var parts []string
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		r, size := utf8.DecodeRuneInString(part)
+		parts = append(parts, string(unicode.ToUpper(r))+part[size:])
+	}
+	s := strings.Join(parts, "")
+	if r, _ := utf8.DecodeRuneInString(s); !unicode.IsUpper(r) || !token.IsIdentifier(s) {
+		s = "X" + s
+	}
+	return s
+}
+
+// lowerFirst make the first letter of s lower case
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+// unexported convert python name to go param name which is not a keyword or a name used by generated code
+func unexported(name string) string {
+	s := lowerFirst(exported(name))
+	if token.IsKeyword(s) || reservedNames[s] {
+		s += "Param"
+	}
+	return s
+}
+
+// docLines split python docstring to lines of go comment
+func docLines(doc string) []string {
+	if len(strings.TrimSpace(doc)) < 1 {
+		return nil
+	}
+	lines := []string{""}
+	for _, line := range strings.Split(strings.TrimRight(doc, "\n"), "\n") {
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	return lines
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// inspectScript is run by python to describe functions and TypedDict/dataclass classes of a script
+// by parsing it with ast, so the script is not imported. It prints json description to stdout.
+const inspectScript = `
+import ast
+import json
+import sys
+
+
+def name_of(node):
+    if isinstance(node, ast.Name):
+        return node.id
+    if isinstance(node, ast.Attribute):
+        return node.attr
+    if isinstance(node, ast.Call):
+        return name_of(node.func)
+    return None
+
+
+def constant_of(node):
+    if hasattr(ast, "Constant") and isinstance(node, ast.Constant):
+        return True, node.value
+    if hasattr(ast, "Str") and isinstance(node, ast.Str):
+        return True, node.s
+    if hasattr(ast, "NameConstant") and isinstance(node, ast.NameConstant):
+        return True, node.value
+    return False, None
+
+
+def type_of(node):
+    if node is None:
+        return None
+    ok, value = constant_of(node)
+    if ok:
+        if value is None:
+            return {"name": "None"}
+        if value is Ellipsis:
+            return {"name": "..."}
+        if isinstance(value, str):
+            return type_of(ast.parse(value, mode="eval").body)
+        return {"name": "Any"}
+    if isinstance(node, (ast.Name, ast.Attribute)):
+        return {"name": name_of(node)}
+    if isinstance(node, ast.Subscript):
+        index = node.slice
+        if hasattr(ast, "Index") and isinstance(index, ast.Index):
+            index = index.value
+        elts = index.elts if isinstance(index, ast.Tuple) else [index]
+        return {"name": name_of(node.value), "args": [type_of(e) for e in elts]}
+    if isinstance(node, ast.BinOp) and isinstance(node.op, ast.BitOr):
+        return {"name": "Union", "args": [type_of(node.left), type_of(node.right)]}
+    if hasattr(ast, "Ellipsis") and isinstance(node, ast.Ellipsis):
+        return {"name": "..."}
+    return {"name": "Any"}
+
+
+def default_of(node):
+    try:
+        return json.dumps(ast.literal_eval(node))
+    except Exception:
+        return "..."
+
+
+def param(arg, kind, default=None):
+    info = {"name": arg.arg, "kind": kind, "type": type_of(arg.annotation)}
+    if default is not None:
+        info["default"] = default_of(default)
+    return info
+
+
+def function(node):
+    args = node.args
+    positional_only = list(getattr(args, "posonlyargs", []))
+    positional = positional_only + list(args.args)
+    defaults = [None] * (len(positional) - len(args.defaults)) + list(args.defaults)
+    params = [param(a, "positional_only" if a in positional_only else "positional", d) for a, d in zip(positional, defaults)]
+    if args.vararg:
+        params.append(param(args.vararg, "varargs"))
+    for a, d in zip(args.kwonlyargs, args.kw_defaults):
+        params.append(param(a, "keyword", d))
+    if args.kwarg:
+        params.append(param(args.kwarg, "kwargs"))
+    return {
+        "name": node.name,
+        "params": params,
+        "returns": type_of(node.returns),
+        "doc": ast.get_docstring(node) or "",
+    }
+
+
+def record(node):
+    bases = [name_of(b) for b in node.bases]
+    decorators = [name_of(d) for d in node.decorator_list]
+    if "TypedDict" in bases:
+        kind = "TypedDict"
+    elif "dataclass" in decorators:
+        kind = "dataclass"
+    else:
+        return None
+    fields = []
+    for item in node.body:
+        if isinstance(item, ast.AnnAssign) and isinstance(item.target, ast.Name):
+            fields.append({"name": item.target.id, "type": type_of(item.annotation), "optional": item.value is not None})
+    return {"name": node.name, "kind": kind, "fields": fields, "doc": ast.get_docstring(node) or ""}
+
+
+def inspect_script(path):
+    with open(path) as f:
+        tree = ast.parse(f.read(), path)
+    functions = []
+    records = []
+    for node in tree.body:
+        if isinstance(node, ast.FunctionDef) and not node.name.startswith("_"):
+            functions.append(function(node))
+        elif isinstance(node, ast.ClassDef):
+            r = record(node)
+            if r is not None:
+                records.append(r)
+    return {"functions": functions, "records": records}
+
+
+sys.stdout.write(json.dumps(inspect_script(sys.argv[1])))
+`
+
+// pyType is a python type annotation, like {"name": "List", "args": [{"name": "int"}]}
+type pyType struct {
+	Name string    `json:"name"`
+	Args []*pyType `json:"args"`
+}
+
+type pyParam struct {
+	Name    string  `json:"name"`
+	Kind    string  `json:"kind"`
+	Type    *pyType `json:"type"`
+	Default *string `json:"default"`
+}
+
+type pyFunction struct {
+	Name    string     `json:"name"`
+	Params  []*pyParam `json:"params"`
+	Returns *pyType    `json:"returns"`
+	Doc     string     `json:"doc"`
+}
+
+type pyField struct {
+	Name     string  `json:"name"`
+	Type     *pyType `json:"type"`
+	Optional bool    `json:"optional"`
+}
+
+// pyRecord is a TypedDict or dataclass class
+type pyRecord struct {
+	Name   string     `json:"name"`
+	Kind   string     `json:"kind"`
+	Fields []*pyField `json:"fields"`
+	Doc    string     `json:"doc"`
+}
+
+type pyScript struct {
+	Functions []*pyFunction `json:"functions"`
+	Records   []*pyRecord   `json:"records"`
+}
+
+// inspect describe the python script by the python executable
+func inspect(executable string, scriptPath string) (*pyScript, error) {
+	cmd := exec.Command(executable, "-c", inspectScript, scriptPath)
+	bs, err := cmd.Output()
+	if err != nil {
+		if e, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("inspect python script error: %v\n%s", err, e.Stderr)
+		}
+		return nil, fmt.Errorf("inspect python script error: %v", err)
+	}
+	script := &pyScript{}
+	if err := json.Unmarshal(bs, script); err != nil {
+		return nil, fmt.Errorf("unexpected python script description: %v", err)
+	}
+	return script, nil
+}
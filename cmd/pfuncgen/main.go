@@ -0,0 +1,66 @@
+// Command pfuncgen generates typed go wrappers of functions in a python script.
+//
+// It parses the script with ast of the python interpreter, so the script is not imported, and reads
+// type annotations, default values and docstrings of public module level functions. A go function is
+// generated for every python function, and a go struct for every TypedDict or dataclass class.
+// Params with default values are collected in an options struct, whose nil fields are not passed.
+//
+// It is usable from go:generate, for example:
+//
+//	//go:generate go run github.com/gitpillow/pfunc/cmd/pfuncgen -script scripts/model.py
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	scriptPath := flag.String("script", "", "path of python script")
+	runtimePath := flag.String("path", "", "path of python script used by generated code at runtime, default is -script")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "package of generated file, default is $GOPACKAGE set by go generate")
+	output := flag.String("o", "", "generated file, default is <script name>_pfunc.go")
+	executable := flag.String("python", "python", "python executable used to parse script, python 3 is needed to read annotations")
+	funcs := flag.String("funcs", "", "comma separated python functions to wrap, default is all public functions")
+	flag.Parse()
+
+	if len(*scriptPath) < 1 {
+		fmt.Fprintln(os.Stderr, "pfuncgen: -script is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c := config{
+		scriptPath:  *scriptPath,
+		runtimePath: *runtimePath,
+		pkg:         *pkg,
+		executable:  *executable,
+	}
+	if len(c.runtimePath) < 1 {
+		c.runtimePath = c.scriptPath
+	}
+	if len(c.pkg) < 1 {
+		c.pkg = "main"
+	}
+	if len(*funcs) > 0 {
+		c.funcs = strings.Split(*funcs, ",")
+	}
+	if len(*output) < 1 {
+		base := filepath.Base(c.scriptPath)
+		*output = strings.TrimSuffix(base, filepath.Ext(base)) + "_pfunc.go"
+	}
+
+	bs, err := generate(c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pfuncgen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(*output, bs, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "pfuncgen: %v\n", err)
+		os.Exit(1)
+	}
+}
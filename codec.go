@@ -0,0 +1,1077 @@
+package pfunc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// key of json objects holding a tagged value, like {"__pfunc__": "datetime", "value": "2020-01-02T03:04:05+08:00"}
+const codecTag = "__pfunc__"
+
+// tags of values which json can not represent
+const (
+	tagDatetime  = "datetime"
+	tagDate      = "date"
+	tagTimedelta = "timedelta"
+	tagBytes     = "bytes"
+	tagDecimal   = "decimal"
+	tagComplex   = "complex"
+	tagObject    = "object"
+	tagDict      = "dict"
+)
+
+// layout of datetime values sent to python, python keeps microseconds only
+const datetimeLayout = "2006-01-02T15:04:05.000000-07:00"
+
+// pythonCodecRuntime is the part of PythonRuntime encoding and decoding tagged values
+const pythonCodecRuntime string = `
+
+def pfunc_encode(value):
+    import base64
+    import datetime
+    import decimal
+    for encoder in pfunc_encoders():
+        encoded = encoder(value)
+        if encoded is not NotImplemented:
+            return pfunc_prepare(encoded)
+    if isinstance(value, datetime.datetime):
+        return {"__pfunc__": "datetime", "value": value.isoformat()}
+    if isinstance(value, datetime.date):
+        return {"__pfunc__": "date", "value": value.isoformat()}
+    if isinstance(value, datetime.timedelta):
+        return {"__pfunc__": "timedelta", "value": (value.days * 86400 + value.seconds) * 1000000 + value.microseconds}
+    if isinstance(value, decimal.Decimal):
+        return {"__pfunc__": "decimal", "value": str(value)}
+    if isinstance(value, complex):
+        return {"__pfunc__": "complex", "value": [value.real, value.imag]}
+    # bytes is str in python 2, which is text
+    if isinstance(value, (bytes, bytearray)) and not isinstance(value, str):
+        return {"__pfunc__": "bytes", "value": base64.b64encode(bytes(value)).decode("ascii")}
+    if isinstance(value, (set, frozenset)):
+        try:
+            return pfunc_prepare(sorted(value))
+        except TypeError:
+            return pfunc_prepare(list(value))
+    if callable(getattr(value, "__pfunc__", None)):
+        return pfunc_prepare(value.__pfunc__())
+    if hasattr(type(value), "__dataclass_fields__"):
+        import dataclasses
+        return pfunc_escape(dict((f.name, pfunc_prepare(getattr(value, f.name))) for f in dataclasses.fields(value)))
+    if not pfunc_encoding.get("no_objects") and pfunc_is_object(value):
+        return pfunc_escape(dict((k, pfunc_prepare(v)) for k, v in pfunc_attributes(value).items()))
+    raise TypeError(repr(value) + " is not JSON serializable")
+
+
+# decode a tagged object, objects are only made of classes in the list go sends apart from the json
+def pfunc_decode(obj, classes=()):
+    tag = obj.get("__pfunc__")
+    if tag is None:
+        return obj
+    import base64
+    import datetime
+    import decimal
+    value = obj.get("value")
+    if tag == "datetime":
+        return pfunc_parse_datetime(value)
+    if tag == "date":
+        return pfunc_parse_datetime(value).date()
+    if tag == "timedelta":
+        return datetime.timedelta(microseconds=value)
+    if tag == "bytes":
+        return base64.b64decode(value)
+    if tag == "decimal":
+        return decimal.Decimal(value)
+    if tag == "complex":
+        return complex(value[0], value[1])
+    if tag == "dict":
+        return dict((k, v) for k, v in value)
+    if tag == "object":
+        if value["class"] not in classes:
+            raise ValueError("class " + repr(value["class"]) + " is not a class of go structs sent to python")
+        return pfunc_class(value["class"])(**value["fields"])
+    return obj
+
+
+pfunc_classes = {}
+
+
+def pfunc_class(path):
+    if path not in pfunc_classes:
+        import importlib
+        module, name = path.rsplit(".", 1)
+        pfunc_classes[path] = pfunc_resolve(importlib.import_module(module), name)
+    return pfunc_classes[path]
+
+
+def pfunc_parse_datetime(value):
+    import datetime
+    import re
+    m = re.match(r"(\d+)-(\d+)-(\d+)(?:T(\d+):(\d+):(\d+)(?:\.(\d+))?)?(Z|[+-]\d\d:\d\d)?$", value)
+    if m is None:
+        raise ValueError("invalid datetime: " + value)
+    parts = [int(p or 0) for p in m.groups()[:6]]
+    microsecond = int((m.group(7) or "0")[:6].ljust(6, "0"))
+    tz = None
+    if m.group(8):
+        offset = 0
+        if m.group(8) != "Z":
+            offset = int(m.group(8)[1:3]) * 60 + int(m.group(8)[4:6])
+            if m.group(8)[0] == "-":
+                offset = -offset
+        tz = pfunc_timezone(offset)
+    return datetime.datetime(*parts, microsecond=microsecond, tzinfo=tz)
+
+
+def pfunc_timezone(minutes):
+    import datetime
+    offset = datetime.timedelta(minutes=minutes)
+    if hasattr(datetime, "timezone"):
+        return datetime.timezone(offset)
+
+    class FixedOffset(datetime.tzinfo):
+        def utcoffset(self, dt):
+            return offset
+
+        def dst(self, dt):
+            return datetime.timedelta(0)
+
+        def tzname(self, dt):
+            return None
+
+    return FixedOffset()
+
+
+def pfunc_dumps(value):
+    return json.dumps(pfunc_prepare(value), default=pfunc_encode)
+
+
+def pfunc_loads(data, classes=()):
+    return json.loads(data, object_hook=lambda obj: pfunc_decode(obj, classes))
+
+
+def pfunc_revive(value, classes=()):
+    # decode tagged values of json loaded without pfunc_loads
+    if isinstance(value, dict):
+        return pfunc_decode(dict((k, pfunc_revive(v, classes)) for k, v in value.items()), classes)
+    if isinstance(value, list):
+        return [pfunc_revive(v, classes) for v in value]
+    return value
+
+
+# escape a dict having the key of tagged objects as a list of its items, so go does not decode it as one
+def pfunc_escape(value):
+    if "__pfunc__" not in value:
+        return value
+    return {"__pfunc__": "dict", "value": [[k, v] for k, v in value.items()]}
+`
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	bigIntType    = reflect.TypeOf(big.Int{})
+	bigFloatType  = reflect.TypeOf(big.Float{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textType      = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	unmarshalType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	untextType    = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// taggedValue is a tagged json object
+type taggedValue struct {
+	Tag   string          `json:"__pfunc__"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Marshal encode v to json like encoding/json, except values python has own types for, which are tagged objects
+// decoded by python: time.Time is a datetime.datetime with timezone, time.Duration is a datetime.timedelta, both
+// rounded to microseconds, []byte is bytes, big.Float is a decimal.Decimal and complex is complex. big.Int is a json
+// number, which is an int of arbitrary precision in python. A struct with a field tagged like
+// `pfunc:"class=models.Person"`, usually a blank field of type struct{}, is an instance of the python class, made by
+// calling it with fields as keyword arguments. Fields are named by pfunc tags like `pfunc:"full_name"`, then by json
+// tags, in both directions. Params of invocations are encoded by Marshal.
+func Marshal(v interface{}) ([]byte, error) {
+	bs, _, err := marshalClasses(v)
+	return bs, err
+}
+
+// marshalClasses is Marshal, and also return the python classes of tagged objects in v. Python only makes
+// instances of classes in this list, which is sent apart from the json, so a map can not name other classes.
+func marshalClasses(v interface{}) ([]byte, []string, error) {
+	classes := map[string]bool{}
+	e, err := encodeValue(reflect.ValueOf(v), classes)
+	if err != nil {
+		return nil, nil, err
+	}
+	bs, err := json.Marshal(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	list := make([]string, 0, len(classes))
+	for class := range classes {
+		list = append(list, class)
+	}
+	sort.Strings(list)
+	return bs, list, nil
+}
+
+// Unmarshal decode json returned by python into v like encoding/json, and decode tagged objects of Marshal, which
+// are also made by python for datetime.datetime, datetime.date, datetime.timedelta, bytes, decimal.Decimal and
+// complex values. Tagged objects in interface{} become time.Time, time.Duration, []byte, *big.Float and complex128.
+// Naive python datetimes are in UTC. Ints of any size can be decoded into big.Int, and decimals into floats too.
+// Ints in interface{} beyond 2^53, which float64 can not hold exactly, become *big.Int instead of float64.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return json.Unmarshal(data, v)
+	}
+	if !bytes.Contains(data, []byte(codecTag)) && !plainNeedsCodec(rv.Type().Elem()) && !hasLargeInt(data) {
+		return json.Unmarshal(data, v)
+	}
+	return decodeValue(data, rv.Elem())
+}
+
+// encodeValue return a value encoding/json marshals to the json of v with tagged objects, and add python classes
+// of the tagged objects to classes
+func encodeValue(v reflect.Value, classes map[string]bool) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	t := v.Type()
+	if !v.CanInterface() {
+		return nil, fmt.Errorf("can not encode value of unexported field: %v", t)
+	}
+	if !needsCodec(t, encodeCodec) {
+		return v.Interface(), nil
+	}
+	switch t {
+	case timeType:
+		return tagged(tagDatetime, v.Interface().(time.Time).Round(time.Microsecond).Format(datetimeLayout)), nil
+	case durationType:
+		return tagged(tagTimedelta, int64(v.Interface().(time.Duration).Round(time.Microsecond)/time.Microsecond)), nil
+	case bigIntType:
+		x := v.Interface().(big.Int)
+		return json.Number(x.String()), nil
+	case bigFloatType:
+		x := v.Interface().(big.Float)
+		return tagged(tagDecimal, x.Text('g', -1)), nil
+	}
+	switch t.Kind() {
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return tagged(tagComplex, []float64{real(c), imag(c)}), nil
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(v.Elem(), classes)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return tagged(tagBytes, base64.StdEncoding.EncodeToString(v.Bytes())), nil
+		}
+		fallthrough
+	case reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := encodeValue(v.Index(i), classes)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := encodeKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			if m[key], err = encodeValue(iter.Value(), classes); err != nil {
+				return nil, err
+			}
+		}
+		return escapeDict(m), nil
+	case reflect.Struct:
+		m := map[string]interface{}{}
+		for _, f := range jsonFields(t) {
+			fv, ok := fieldByIndex(v, f.index, false)
+			if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+				continue
+			}
+			e, err := encodeValue(fv, classes)
+			if err != nil {
+				return nil, err
+			}
+			m[f.name] = e
+		}
+		if class := pythonClass(t); len(class) > 0 {
+			classes[class] = true
+			return tagged(tagObject, map[string]interface{}{"class": class, "fields": escapeDict(m)}), nil
+		}
+		return escapeDict(m), nil
+	}
+	return v.Interface(), nil
+}
+
+func tagged(tag string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{codecTag: tag, "value": value}
+}
+
+// escapeDict return m, or a tagged list of its key and value pairs when it has the key of tagged objects,
+// so it is not decoded as a tagged object
+func escapeDict(m map[string]interface{}) interface{} {
+	if _, ok := m[codecTag]; !ok {
+		return m
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]interface{}, len(keys))
+	for i, k := range keys {
+		items[i] = []interface{}{k, m[k]}
+	}
+	return tagged(tagDict, items)
+}
+
+// encodeKey return json object key of map key like encoding/json
+func encodeKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		bs, err := tm.MarshalText()
+		return string(bs), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type: %v", k.Type())
+}
+
+// decodeValue decode json data into v, which is settable
+func decodeValue(data []byte, v reflect.Value) error {
+	data = bytes.TrimSpace(data)
+	t := v.Type()
+	if bytes.Equal(data, []byte("null")) || !needsCodec(t, decodeCodec) && !mayHoldDict(t, data) {
+		return unmarshalInto(data, v)
+	}
+	tag, value, isTagged := parseTagged(data)
+
+	switch t {
+	case timeType:
+		if !isTagged {
+			return unmarshalInto(data, v)
+		}
+		tm, err := decodeTime(tag, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	case durationType:
+		if !isTagged {
+			return unmarshalInto(data, v)
+		}
+		d, err := decodeDuration(tag, value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case bigFloatType:
+		f, err := decodeBigFloat(data, tag, value, isTagged)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(*f))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Complex64, reflect.Complex128:
+		c, err := decodeComplex(data, tag, value, isTagged)
+		if err != nil {
+			return err
+		}
+		v.SetComplex(c)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if !isTagged || tag != tagDecimal {
+			return unmarshalInto(data, v)
+		}
+		f, err := decodeBigFloat(data, tag, value, isTagged)
+		if err != nil {
+			return err
+		}
+		x, _ := f.Float64()
+		v.SetFloat(x)
+		return nil
+	case reflect.Interface:
+		if t.NumMethod() > 0 {
+			return unmarshalInto(data, v)
+		}
+		i, err := decodeInterface(data, tag, value, isTagged)
+		if err != nil {
+			return err
+		}
+		if i == nil {
+			v.Set(reflect.Zero(t))
+		} else {
+			v.Set(reflect.ValueOf(i))
+		}
+		return nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return decodeValue(data, v.Elem())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if !isTagged {
+				return unmarshalInto(data, v)
+			}
+			bs, err := decodeBytes(tag, value)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(bs)
+			return nil
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := decodeValue(item, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Array:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		for i := 0; i < v.Len(); i++ {
+			if i >= len(items) {
+				v.Index(i).Set(reflect.Zero(t.Elem()))
+			} else if err := decodeValue(items[i], v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if isTagged && tag == tagDict {
+			data = dictItems(value)
+		}
+		return decodeMap(data, v)
+	case reflect.Struct:
+		if isTagged && tag == tagObject {
+			data = objectFields(value)
+		}
+		if isTagged && tag == tagDict {
+			data = dictItems(value)
+		}
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		fields := jsonFields(t)
+		for k, item := range items {
+			f := findField(fields, k)
+			if f == nil {
+				continue
+			}
+			fv, _ := fieldByIndex(v, f.index, true)
+			if !fv.CanSet() {
+				continue
+			}
+			if err := decodeValue(item, fv); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return unmarshalInto(data, v)
+}
+
+// decodeMap decode json object data into map v, its keys are not looked at as the tag of a tagged object
+func decodeMap(data []byte, v reflect.Value) error {
+	t := v.Type()
+	var items map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, len(items)))
+	}
+	for k, item := range items {
+		key := reflect.New(t.Key()).Elem()
+		if err := decodeKey(k, key); err != nil {
+			return err
+		}
+		value := reflect.New(t.Elem()).Elem()
+		if err := decodeValue(item, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, value)
+	}
+	return nil
+}
+
+// unmarshalInto decode data into v by encoding/json
+func unmarshalInto(data []byte, v reflect.Value) error {
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	if err := json.Unmarshal(data, p.Interface()); err != nil {
+		return err
+	}
+	v.Set(p.Elem())
+	return nil
+}
+
+// parseTagged return tag and value of a tagged object
+func parseTagged(data []byte) (string, json.RawMessage, bool) {
+	if len(data) < 1 || data[0] != '{' {
+		return "", nil, false
+	}
+	tv := taggedValue{}
+	if err := json.Unmarshal(data, &tv); err != nil || len(tv.Tag) < 1 {
+		return "", nil, false
+	}
+	return tv.Tag, tv.Value, true
+}
+
+func decodeTime(tag string, value json.RawMessage) (time.Time, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return time.Time{}, fmt.Errorf("invalid %v value: %s", tag, value)
+	}
+	switch tag {
+	case tagDatetime:
+		if tm, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return tm, nil
+		}
+		return time.Parse("2006-01-02T15:04:05.999999999", s)
+	case tagDate:
+		return time.Parse("2006-01-02", s)
+	}
+	return time.Time{}, fmt.Errorf("can not decode %v value into time.Time", tag)
+}
+
+func decodeDuration(tag string, value json.RawMessage) (time.Duration, error) {
+	if tag != tagTimedelta {
+		return 0, fmt.Errorf("can not decode %v value into time.Duration", tag)
+	}
+	var us int64
+	if err := json.Unmarshal(value, &us); err != nil {
+		return 0, fmt.Errorf("invalid %v value: %s", tag, value)
+	}
+	if us > math.MaxInt64/int64(time.Microsecond) || us < math.MinInt64/int64(time.Microsecond) {
+		return 0, fmt.Errorf("%v value overflows time.Duration: %s", tag, value)
+	}
+	return time.Duration(us) * time.Microsecond, nil
+}
+
+func decodeBytes(tag string, value json.RawMessage) ([]byte, error) {
+	var bs []byte
+	if tag != tagBytes {
+		return nil, fmt.Errorf("can not decode %v value into []byte", tag)
+	}
+	if err := json.Unmarshal(value, &bs); err != nil {
+		return nil, fmt.Errorf("invalid %v value: %s", tag, value)
+	}
+	return bs, nil
+}
+
+// decodeBigFloat decode a decimal value or a json number with all its digits
+func decodeBigFloat(data []byte, tag string, value json.RawMessage, isTagged bool) (*big.Float, error) {
+	s := string(data)
+	if isTagged {
+		if tag != tagDecimal || json.Unmarshal(value, &s) != nil {
+			return nil, fmt.Errorf("can not decode %v value into big.Float", tag)
+		}
+		// python writes infinity as Infinity, which big.Float does not parse
+		s = strings.Replace(s, "Infinity", "Inf", 1)
+	} else if len(s) > 0 && s[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, err
+		}
+	}
+	// enough bits for all decimal digits, and no less than float64
+	prec := uint(len(s))*4 + 64
+	f, _, err := big.ParseFloat(s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil, fmt.Errorf("invalid decimal value %q: %v", s, err)
+	}
+	return f, nil
+}
+
+func decodeComplex(data []byte, tag string, value json.RawMessage, isTagged bool) (complex128, error) {
+	if !isTagged {
+		var f float64
+		if err := json.Unmarshal(data, &f); err != nil {
+			return 0, fmt.Errorf("can not decode %s into complex", data)
+		}
+		return complex(f, 0), nil
+	}
+	var parts [2]float64
+	if tag != tagComplex || json.Unmarshal(value, &parts) != nil {
+		return 0, fmt.Errorf("can not decode %v value %s into complex", tag, value)
+	}
+	return complex(parts[0], parts[1]), nil
+}
+
+// decodeInterface decode data like encoding/json does into interface{}, and tagged objects into go types
+func decodeInterface(data []byte, tag string, value json.RawMessage, isTagged bool) (interface{}, error) {
+	if isTagged {
+		switch tag {
+		case tagDatetime, tagDate:
+			return decodeTime(tag, value)
+		case tagTimedelta:
+			return decodeDuration(tag, value)
+		case tagBytes:
+			return decodeBytes(tag, value)
+		case tagDecimal:
+			return decodeBigFloat(data, tag, value, isTagged)
+		case tagComplex:
+			return decodeComplex(data, tag, value, isTagged)
+		case tagObject:
+			return decodeInterface(objectFields(value), "", nil, false)
+		case tagDict:
+			return decodeInterface(dictItems(value), "", nil, false)
+		}
+		return nil, fmt.Errorf("unknown tagged value: %v", tag)
+	}
+	switch data[0] {
+	case '[':
+		var items []interface{}
+		if err := decodeValue(data, reflect.ValueOf(&items).Elem()); err != nil {
+			return nil, err
+		}
+		return items, nil
+	case '{':
+		var m map[string]interface{}
+		if err := decodeMap(data, reflect.ValueOf(&m).Elem()); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	if n, ok := largeInt(data); ok {
+		return n, nil
+	}
+	var i interface{}
+	err := json.Unmarshal(data, &i)
+	return i, err
+}
+
+// ints of float64 are exact up to 2^53
+var maxExactInt = new(big.Int).Lsh(big.NewInt(1), 53)
+
+// largeInt decode json integer data which float64 can not hold exactly into *big.Int
+func largeInt(data []byte) (*big.Int, bool) {
+	if len(data) < 1 || data[0] != '-' && (data[0] < '0' || data[0] > '9') || bytes.ContainsAny(data, ".eE") {
+		return nil, false
+	}
+	n, ok := new(big.Int).SetString(string(data), 10)
+	if !ok || n.CmpAbs(maxExactInt) <= 0 {
+		return nil, false
+	}
+	return n, true
+}
+
+// hasLargeInt tells if json data may hold integers beyond float64 precision, which are numbers of 16 or more
+// digits outside strings
+func hasLargeInt(data []byte) bool {
+	inString, escaped, digits := false, false, 0
+	for _, c := range data {
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString, digits = true, 0
+		case c >= '0' && c <= '9':
+			digits++
+			if digits >= 16 {
+				return true
+			}
+		case c == '-':
+		default:
+			digits = 0
+		}
+	}
+	return false
+}
+
+// objectFields return json of fields of an object value
+func objectFields(value json.RawMessage) json.RawMessage {
+	var object struct {
+		Fields json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(value, &object); err != nil || len(object.Fields) < 1 {
+		return json.RawMessage("null")
+	}
+	return object.Fields
+}
+
+// dictItems return json object of the key and value pairs of an escaped dict value, keys which are not json
+// strings are keys by their json like python writes them
+func dictItems(value json.RawMessage) json.RawMessage {
+	var pairs [][2]json.RawMessage
+	if err := json.Unmarshal(value, &pairs); err != nil {
+		return json.RawMessage("null")
+	}
+	items := make(map[string]json.RawMessage, len(pairs))
+	for _, pair := range pairs {
+		key := string(pair[0])
+		if err := json.Unmarshal(pair[0], &key); err != nil {
+			key = string(pair[0])
+		}
+		items[key] = pair[1]
+	}
+	bs, err := json.Marshal(items)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return bs
+}
+
+// decodeKey decode json object key into map key k like encoding/json
+func decodeKey(s string, k reflect.Value) error {
+	if k.Kind() == reflect.String {
+		k.SetString(s)
+		return nil
+	}
+	if reflect.PtrTo(k.Type()).Implements(untextType) {
+		return k.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, k.Type().Bits())
+		k.SetInt(n)
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, k.Type().Bits())
+		k.SetUint(n)
+		return err
+	}
+	return fmt.Errorf("unsupported map key type: %v", k.Type())
+}
+
+// codec direction of needsCodec
+type codecDirection int
+
+const (
+	encodeCodec codecDirection = iota
+	decodeCodec
+)
+
+type codecKey struct {
+	t         reflect.Type
+	direction codecDirection
+}
+
+var codecTypes sync.Map
+
+// needsCodec tells if values of t may hold values encoding/json does not encode or decode like python expects,
+// other values are left to encoding/json
+func needsCodec(t reflect.Type, direction codecDirection) bool {
+	key := codecKey{t, direction}
+	if needs, ok := codecTypes.Load(key); ok {
+		return needs.(bool)
+	}
+	needs := typeNeedsCodec(t, direction, map[reflect.Type]bool{})
+	codecTypes.Store(key, needs)
+	return needs
+}
+
+// typeNeedsCodec look for types needing codec reachable from t, seen types are already being looked at
+func typeNeedsCodec(t reflect.Type, direction codecDirection, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t {
+	case timeType, durationType, bigFloatType:
+		return true
+	case bigIntType:
+		return direction == encodeCodec
+	}
+	// pointers of big numbers implement json interfaces, but are encoded by codec too
+	if t.Kind() == reflect.Ptr && (t.Elem() == bigFloatType || (t.Elem() == bigIntType && direction == encodeCodec)) {
+		return true
+	}
+	if direction == encodeCodec && (t.Implements(marshalerType) || t.Implements(textType)) {
+		return false
+	}
+	if direction == decodeCodec && (reflect.PtrTo(t).Implements(unmarshalType) || reflect.PtrTo(t).Implements(untextType)) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Complex64, reflect.Complex128, reflect.Interface:
+		return true
+	case reflect.Float32, reflect.Float64:
+		return direction == decodeCodec
+	case reflect.Map:
+		// maps may have the key of tagged objects, which is escaped
+		return direction == encodeCodec || typeNeedsCodec(t.Elem(), direction, seen)
+	case reflect.Ptr, reflect.Array:
+		return typeNeedsCodec(t.Elem(), direction, seen)
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8 || typeNeedsCodec(t.Elem(), direction, seen)
+	case reflect.Struct:
+		if len(pythonClass(t)) > 0 {
+			return true
+		}
+		for _, f := range jsonFields(t) {
+			if f.renamed || f.name == codecTag || typeNeedsCodec(t.FieldByIndex(f.index).Type, direction, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mayHoldDict tells if data may hold escaped dicts which encoding/json can not decode into t, types decoding json
+// by themselves get the escaped dicts
+func mayHoldDict(t reflect.Type, data []byte) bool {
+	if reflect.PtrTo(t).Implements(unmarshalType) || reflect.PtrTo(t).Implements(untextType) {
+		return false
+	}
+	return bytes.Contains(data, []byte(codecTag))
+}
+
+// plainNeedsCodec tells if t holds values which encoding/json can not decode from plain json numbers
+func plainNeedsCodec(t reflect.Type) bool {
+	return plainCodec(t, map[reflect.Type]bool{})
+}
+
+func plainCodec(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	if t == bigFloatType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Ptr, reflect.Array, reflect.Slice, reflect.Map:
+		return plainCodec(t.Elem(), seen)
+	case reflect.Struct:
+		for _, f := range jsonFields(t) {
+			if f.renamed || plainCodec(t.FieldByIndex(f.index).Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var pythonClasses sync.Map
+
+// pythonClass return the python class of struct t, which is set by a pfunc tag like "class=models.Person"
+// of one of its fields, usually a blank field of type struct{}
+func pythonClass(t reflect.Type) string {
+	if class, ok := pythonClasses.Load(t); ok {
+		return class.(string)
+	}
+	class := ""
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("pfunc"); strings.HasPrefix(tag, "class=") {
+			class = strings.TrimPrefix(tag, "class=")
+			break
+		}
+	}
+	pythonClasses.Store(t, class)
+	return class
+}
+
+// jsonField is a field of struct encoded by encoding/json
+type jsonField struct {
+	name      string
+	index     []int
+	tagged    bool
+	omitEmpty bool
+	renamed   bool
+}
+
+var structFields sync.Map
+
+// jsonFields return fields of struct t named like encoding/json does, fields of embedded structs are promoted
+func jsonFields(t reflect.Type) []jsonField {
+	if fields, ok := structFields.Load(t); ok {
+		return fields.([]jsonField)
+	}
+	var all []jsonField
+	collectFields(t, nil, map[reflect.Type]bool{}, &all)
+
+	// a name is taken by the shallowest field, then the tagged one, others of same depth are ambiguous
+	byName := map[string][]jsonField{}
+	var names []string
+	for _, f := range all {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+	fields := []jsonField{}
+	for _, name := range names {
+		if f, ok := dominantField(byName[name]); ok {
+			fields = append(fields, f)
+		}
+	}
+	structFields.Store(t, fields)
+	return fields
+}
+
+// dominantField return the shallowest field of same name, or the tagged one of them, false if it is ambiguous
+func dominantField(candidates []jsonField) (jsonField, bool) {
+	depth := len(candidates[0].index)
+	for _, f := range candidates {
+		if len(f.index) < depth {
+			depth = len(f.index)
+		}
+	}
+	var shallowest, tagged []jsonField
+	for _, f := range candidates {
+		if len(f.index) == depth {
+			shallowest = append(shallowest, f)
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+	}
+	if len(shallowest) == 1 {
+		return shallowest[0], true
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return jsonField{}, false
+}
+
+func collectFields(t reflect.Type, index []int, visited map[reflect.Type]bool, fields *[]jsonField) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		rename := sf.Tag.Get("pfunc")
+		if strings.HasPrefix(rename, "class=") {
+			rename = ""
+		}
+		if rename == "-" || (tag == "-" && len(rename) < 1) {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, options = tag[:comma], tag[comma:]
+		}
+		if len(rename) > 0 {
+			name = rename
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && len(name) < 1 && ft.Kind() == reflect.Struct {
+			collectFields(ft, fieldIndex, visited, fields)
+			continue
+		}
+		if len(sf.PkgPath) > 0 {
+			continue
+		}
+		tagged := len(name) > 0
+		if !tagged {
+			name = sf.Name
+		}
+		*fields = append(*fields, jsonField{name: name, index: fieldIndex, tagged: tagged, omitEmpty: strings.Contains(options, ",omitempty"), renamed: len(rename) > 0})
+	}
+}
+
+// findField return field named key, or matching key case insensitively like encoding/json
+func findField(fields []jsonField, key string) *jsonField {
+	for i := range fields {
+		if fields[i].name == key {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, key) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// fieldByIndex return the field of struct v, embedded nil pointers are allocated when alloc is set
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isEmptyValue tells if v is omitted by omitempty
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
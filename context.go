@@ -0,0 +1,37 @@
+package pfunc
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// ErrTimeout is the exception of an invocation killed because deadline of its context passed
+var ErrTimeout = errors.New("python function timeout")
+
+// ErrCanceled is the exception of an invocation killed because its context was canceled
+var ErrCanceled = errors.New("python function canceled")
+
+// contextError map error of a done context to ErrTimeout or ErrCanceled
+func contextError(err error) error {
+	if err == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return ErrCanceled
+}
+
+// waitContext wait the started command to exit, kill it and its children when the context is done first
+func waitContext(ctx context.Context, cmd *exec.Cmd) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		killProcessGroup(cmd)
+		return <-done
+	}
+}
@@ -0,0 +1,107 @@
+package pfunc
+
+import (
+	"encoding/json"
+	"os/exec"
+)
+
+// environment variable telling temp script and pool workers how to encode values json has no type for
+const encodingEnv = "PFUNC_ENCODING"
+
+// pythonEncodingRuntime is the part of PythonRuntime preparing values json has no type for, as configured by Encoding
+const pythonEncodingRuntime string = `
+
+pfunc_encoding = {}
+
+
+def pfunc_configure_encoding():
+    config = os.environ.pop("PFUNC_ENCODING", "")
+    if config:
+        pfunc_encoding.update(json.loads(config))
+
+
+def pfunc_encoders():
+    # encoders are imported on first use like functions, after sys.path of invocation is set
+    if "functions" not in pfunc_encoding:
+        import importlib
+        functions = []
+        for path in pfunc_encoding.get("encoders") or []:
+            module, name = path.rsplit(".", 1)
+            functions.append(pfunc_resolve(importlib.import_module(module), name))
+        pfunc_encoding["functions"] = functions
+    return pfunc_encoding["functions"]
+
+
+def pfunc_prepare(value):
+    # json encodes tuples and int or str enums before calling default, so they are encoded here
+    if type(value) in pfunc_scalars:
+        return value
+    if isinstance(value, dict):
+        return pfunc_escape(dict((k, pfunc_prepare(v)) for k, v in value.items()))
+    if isinstance(value, tuple) and hasattr(value, "_fields") and not pfunc_encoding.get("namedtuple_as_list"):
+        return dict((k, pfunc_prepare(v)) for k, v in zip(value._fields, value))
+    if isinstance(value, (list, tuple)):
+        return [v if type(v) in pfunc_scalars else pfunc_prepare(v) for v in value]
+    if [c for c in type(value).__mro__ if c.__name__ == "Enum" and c.__module__ == "enum"]:
+        return value.name if pfunc_encoding.get("enum_names") else pfunc_prepare(value.value)
+    return value
+
+
+pfunc_scalars = (int, float, bool, str, type(u""), type(None))
+
+
+def pfunc_is_object(value):
+    import types
+    if isinstance(value, (type, types.ModuleType, types.FunctionType, types.MethodType, types.BuiltinFunctionType)):
+        return False
+    return hasattr(value, "__dict__") or hasattr(type(value), "__slots__")
+
+
+def pfunc_attributes(value):
+    attributes = {}
+    for c in reversed(type(value).__mro__):
+        slots = getattr(c, "__slots__", ())
+        for name in [slots] if isinstance(slots, str) else slots:
+            if hasattr(value, name):
+                attributes[name] = getattr(value, name)
+    attributes.update(getattr(value, "__dict__", {}))
+    return dict((k, v) for k, v in attributes.items() if not k.startswith("_"))
+`
+
+// Encoding configure how python encodes return values json has no type for. By default dataclasses and namedtuples
+// are maps of their fields, Enum members are their values, objects with a __pfunc__() method are what it returns,
+// and other objects are maps of their public attributes, so they decode into go structs with json tags.
+type Encoding struct {
+	// NamedTupleAsList encode namedtuples as lists, like tuples
+	NamedTupleAsList bool `json:"namedtuple_as_list,omitempty"`
+	// EnumNames encode Enum members by their names instead of values
+	EnumNames bool `json:"enum_names,omitempty"`
+	// NoObjects fail to encode objects without a __pfunc__() method instead of encoding their attributes
+	NoObjects bool `json:"no_objects,omitempty"`
+	// Encoders are python functions like "package.module.encode", called in order with every value json has no type
+	// for before other rules. An encoder returns a value json can encode, or NotImplemented to pass the value on.
+	Encoders []string `json:"encoders,omitempty"`
+}
+
+// WithEncoding set how python encodes return values json has no type for, pool workers use the encoding of runner
+// which started the pool
+func WithEncoding(e Encoding) Option {
+	return func(r *Runner) {
+		e.Encoders = append([]string(nil), e.Encoders...)
+		r.encoding = e
+	}
+}
+
+// configured tells if encoding is not the default one
+func (e Encoding) configured() bool {
+	return e.NamedTupleAsList || e.EnumNames || e.NoObjects || len(e.Encoders) > 0
+}
+
+// setupEncoding tell python of command the encoding of runner
+func (r *Runner) setupEncoding(cmd *exec.Cmd) {
+	if !r.encoding.configured() {
+		return
+	}
+	bs, _ := json.Marshal(r.encoding)
+	cmd.Env = append(cmd.Env, encodingEnv+"="+string(bs))
+}
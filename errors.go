@@ -0,0 +1,162 @@
+package pfunc
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pythonExceptionRuntime is the part of PythonRuntime turning exceptions into json parsed as PythonError
+const pythonExceptionRuntime string = `
+
+def pfunc_text(value):
+    try:
+        return u"{0}".format(value)
+    except Exception:
+        return repr(value)
+
+
+def pfunc_jsonable(value):
+    try:
+        json.dumps(value)
+        return value
+    except Exception:
+        return repr(value)
+
+
+def pfunc_qualname(cls):
+    # classes defined in a function are named from the function locals
+    return getattr(cls, "__qualname__", cls.__name__).split("<locals>.")[-1]
+
+
+def pfunc_exception(e, tb=None, seen=None):
+    if seen is None:
+        seen = set()
+    seen.add(id(e))
+    if tb is None:
+        tb = getattr(e, "__traceback__", None)
+    info = {
+        "type": pfunc_qualname(type(e)),
+        "module": type(e).__module__,
+        "args": [pfunc_jsonable(a) for a in getattr(e, "args", ())],
+        "message": pfunc_text(e),
+        "traceback": [{"file": f, "line": l, "function": n, "source": s} for f, l, n, s in traceback.extract_tb(tb)],
+        "formatted": "".join(traceback.format_exception(type(e), e, tb)),
+    }
+    cause = getattr(e, "__cause__", None)
+    if cause is not None and id(cause) not in seen:
+        info["cause"] = pfunc_exception(cause, None, seen)
+    context = getattr(e, "__context__", None)
+    if context is not None and id(context) not in seen and not getattr(e, "__suppress_context__", False):
+        info["context"] = pfunc_exception(context, None, seen)
+    return info
+`
+
+// PythonError is an exception raised by python code
+type PythonError struct {
+	Type      string        `json:"type"`
+	Module    string        `json:"module"`
+	Args      []interface{} `json:"args"`
+	Message   string        `json:"message"`
+	Traceback []Frame       `json:"traceback"`
+	Cause     *PythonError  `json:"cause"`
+	Context   *PythonError  `json:"context"`
+	Formatted string        `json:"formatted"`
+}
+
+// Frame is one entry of traceback of python exception, the innermost frame is the last one
+type Frame struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"function"`
+	Source   string `json:"source"`
+}
+
+// Error return the traceback text formatted by python, or exception type and message if it is absent
+func (e *PythonError) Error() string {
+	if len(e.Formatted) > 0 {
+		return e.Formatted
+	}
+	if len(e.Message) > 0 {
+		return e.Type + ": " + e.Message
+	}
+	return e.Type
+}
+
+// Unwrap return the explicit cause of exception, or the exception being handled when it was raised
+func (e *PythonError) Unwrap() error {
+	if e.Cause != nil {
+		return e.Cause
+	}
+	if e.Context != nil {
+		return e.Context
+	}
+	return nil
+}
+
+// QualifiedType return exception type name, qualified by its outer classes on python 3, with its module, builtin
+// exceptions has no module
+func (e *PythonError) QualifiedType() string {
+	if len(e.Module) < 1 || e.Module == "builtins" || e.Module == "exceptions" || e.Module == "__builtin__" {
+		return e.Type
+	}
+	return e.Module + "." + e.Type
+}
+
+// exceptionError decode exception section of python output to PythonError,
+// empty section means no exception and is kept as an empty error
+func exceptionError(section string) error {
+	if len(section) < 1 {
+		return errors.New("")
+	}
+	e := &PythonError{}
+	if err := json.Unmarshal([]byte(section), e); err != nil || len(e.Type) < 1 {
+		return errors.New(section)
+	}
+	return e
+}
+
+var tracebackHeader = "Traceback (most recent call last):"
+var tracebackFrameLine = regexp.MustCompile(`^  File "(.*)", line (\d+), in (.*)$`)
+var tracebackMarkerLine = regexp.MustCompile(`^[\^~ ]+$`)
+
+// ParseTraceback parse the last traceback printed by python, return nil if there is no traceback in text
+func ParseTraceback(text string) *PythonError {
+	start := strings.LastIndex(text, tracebackHeader)
+	if start < 0 {
+		return nil
+	}
+
+	e := &PythonError{Formatted: text}
+	lines := strings.Split(strings.TrimRight(text[start+len(tracebackHeader):], "\r\n"), "\n")
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if m := tracebackFrameLine.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[2])
+			e.Traceback = append(e.Traceback, Frame{File: m[1], Line: n, Function: m[3]})
+		} else if strings.HasPrefix(line, "    ") && len(e.Traceback) > 0 {
+			// source is the first line after frame, python 3.11 marks the failed expression in lines below it
+			frame := &e.Traceback[len(e.Traceback)-1]
+			if source := strings.TrimSpace(line); len(frame.Source) < 1 && !tracebackMarkerLine.MatchString(source) {
+				frame.Source = source
+			}
+		} else if len(line) > 0 && !strings.HasPrefix(line, " ") && len(e.Type) < 1 {
+			name := line
+			if i := strings.Index(line, ":"); i > -1 {
+				name = line[:i]
+				e.Message = strings.TrimSpace(line[i+1:])
+			}
+			if i := strings.LastIndex(name, "."); i > -1 {
+				e.Module = name[:i]
+				name = name[i+1:]
+			}
+			e.Type = name
+		}
+	}
+	if len(e.Type) < 1 {
+		return nil
+	}
+	return e
+}
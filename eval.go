@@ -0,0 +1,64 @@
+package pfunc
+
+import "context"
+
+// module of temp scripts and pool workers, where functions of PythonRuntime are defined
+const runtimeModule = "__main__"
+
+// pythonEvalRuntime is the part of PythonRuntime evaluating expressions and running code for Eval and Exec
+const pythonEvalRuntime string = `
+
+def pfunc_eval(expr, variables):
+    return eval(expr, dict(variables or {}))
+
+
+def pfunc_exec(code, variables, names):
+    scope = dict(variables or {})
+    exec(code, scope)
+    result = {}
+    for name in names:
+        if name not in scope:
+            raise NameError("name '{0}' is not defined".format(name))
+        result[name] = scope[name]
+    return result
+`
+
+// Eval evaluate a python expression by the default runner, see Runner.Eval
+func Eval(expr string, vars map[string]interface{}) PResult {
+	return DefaultRunner().Eval(expr, vars)
+}
+
+// Exec run a block of python code by the default runner, see Runner.Exec
+func Exec(code string, vars map[string]interface{}, names ...string) PResult {
+	return DefaultRunner().Exec(code, vars, names...)
+}
+
+// Eval evaluate a python expression with vars bound as its global variables, for example:
+//
+//	result := r.Eval("a + b", map[string]interface{}{"a": 1, "b": 2})
+func (r *Runner) Eval(expr string, vars map[string]interface{}) PResult {
+	return r.EvalContext(context.Background(), expr, vars)
+}
+
+// EvalContext is like Eval, but the python process is killed when the context is done
+func (r *Runner) EvalContext(ctx context.Context, expr string, vars map[string]interface{}) PResult {
+	return r.dispatch(ctx, nil, invocation{module: runtimeModule, funcName: "pfunc_eval", params: []interface{}{expr, vars}})
+}
+
+// Exec run a block of python code with vars bound as its global variables, the result is a json object of
+// the global variables of names after the code ran, for example:
+//
+//	result := r.Exec("import math\nr = math.sqrt(x)", map[string]interface{}{"x": 4}, "r")
+//
+// gives {"r": 2.0}. A name not defined by the code is a NameError.
+func (r *Runner) Exec(code string, vars map[string]interface{}, names ...string) PResult {
+	return r.ExecContext(context.Background(), code, vars, names...)
+}
+
+// ExecContext is like Exec, but the python process is killed when the context is done
+func (r *Runner) ExecContext(ctx context.Context, code string, vars map[string]interface{}, names ...string) PResult {
+	if names == nil {
+		names = []string{}
+	}
+	return r.dispatch(ctx, nil, invocation{module: runtimeModule, funcName: "pfunc_exec", params: []interface{}{code, vars, names}})
+}
@@ -1 +1,2 @@
-go test ./...
\ No newline at end of file
+PFUNC_PYTHON=python2 go test -count=1 ./...
+PFUNC_PYTHON=python3 go test -count=1 ./...
@@ -0,0 +1,98 @@
+package pfunc
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// pythonInvocationRuntime is the part of PythonRuntime resolving dotted names of functions, classes and methods
+const pythonInvocationRuntime string = `
+
+def pfunc_resolve(obj, path):
+    for name in path.split("."):
+        obj = getattr(obj, name)
+    return obj
+`
+
+// invocation describe one call of a python function. The function is imported from module by name when module
+// is set, or from the script. When method is set, funcName is a class, and the method is invoked on an instance
+// constructed with initParams and initKw.
+type invocation struct {
+	module     string
+	scriptPath string
+	funcName   string
+	params     []interface{}
+	kw         map[string]interface{}
+	method     string
+	initParams []interface{}
+	initKw     map[string]interface{}
+}
+
+// dotted path of python attributes like "Model.predict"
+var attributePath = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_]*(\.[\p{L}_][\p{L}\p{N}_]*)*$`)
+
+// check the script exists, and module name, function name and method name are python attribute paths
+func (inv invocation) check() error {
+	if len(inv.module) > 0 {
+		if !attributePath.MatchString(inv.module) {
+			return fmt.Errorf("invoke python function error: invalid python module name: %q", inv.module)
+		}
+	} else if _, err := os.Stat(inv.scriptPath); os.IsNotExist(err) {
+		return fmt.Errorf("invoke python function error: python script not exists: %v: %v", inv.scriptPath, err)
+	}
+	if err := inv.validate(); err != nil {
+		return fmt.Errorf("invoke python function error: %v", err)
+	}
+	return nil
+}
+
+// validate check function name and method name are python attribute paths
+func (inv invocation) validate() error {
+	if !attributePath.MatchString(inv.funcName) {
+		return fmt.Errorf("invalid python function name: %q", inv.funcName)
+	}
+	if len(inv.method) > 0 && !attributePath.MatchString(inv.method) {
+		return fmt.Errorf("invalid python method name: %q", inv.method)
+	}
+	return nil
+}
+
+// importFrom return the module to import function from, and the directory to append to PYTHONPATH
+// when the script is not in any of pythonPaths
+func (inv invocation) importFrom(pythonPaths []string) (string, string) {
+	if len(inv.module) > 0 {
+		return inv.module, ""
+	}
+	from, err := getRelativeImportPath(pythonPaths, inv.scriptPath)
+	if err != nil {
+		return getAbsoluteImportPath(inv.scriptPath)
+	}
+	return from, ""
+}
+
+// importName return the first name of function path, which is imported from the script
+func (inv invocation) importName() string {
+	return strings.SplitN(inv.funcName, ".", 2)[0]
+}
+
+// payload is the json value holding all params of an invocation
+type payload struct {
+	Args       []interface{}          `json:"args"`
+	Kwargs     map[string]interface{} `json:"kwargs"`
+	InitArgs   []interface{}          `json:"init_args,omitempty"`
+	InitKwargs map[string]interface{} `json:"init_kwargs,omitempty"`
+}
+
+// payload return params and keyword params of invocation and of class constructor
+func (inv invocation) payload() payload {
+	p := payload{Args: inv.params, Kwargs: inv.kw, InitArgs: inv.initParams, InitKwargs: inv.initKw}
+	if p.Args == nil {
+		p.Args = []interface{}{}
+	}
+	if p.Kwargs == nil {
+		p.Kwargs = map[string]interface{}{}
+	}
+	return p
+}
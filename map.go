@@ -0,0 +1,149 @@
+package pfunc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// mapConfig holds options of Map
+type mapConfig struct {
+	concurrency   int
+	collectErrors bool
+}
+
+// MapOption configure Map
+type MapOption func(*mapConfig)
+
+// MapConcurrency set the max number of python invocations running at the same time, it is the number of CPUs
+// by default
+func MapConcurrency(n int) MapOption {
+	return func(c *mapConfig) {
+		c.concurrency = n
+	}
+}
+
+// MapCollectErrors make Map invoke all inputs and return all errors as MapErrors, instead of stopping at the
+// first error
+func MapCollectErrors() MapOption {
+	return func(c *mapConfig) {
+		c.collectErrors = true
+	}
+}
+
+// MapError is the error of invocation of one input of Map
+type MapError struct {
+	Index int
+	Err   error
+}
+
+func (e *MapError) Error() string {
+	return fmt.Sprintf("map input %v: %v", e.Index, e.Err)
+}
+
+func (e *MapError) Unwrap() error {
+	return e.Err
+}
+
+// MapErrors is all errors of Map with MapCollectErrors, in the order of inputs
+type MapErrors []*MapError
+
+func (e MapErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%v of map inputs failed: %v", len(e), strings.Join(messages, "; "))
+}
+
+// Map invoke wrapped function for every element of inputs, which must be a slice, in parallel python invocations,
+// for example:
+//
+//	results, err := pfunc.Map(ctx, pfunc.Func("model.py", "score").Return(0.0), []string{"a", "b"}, pfunc.MapConcurrency(4))
+//
+// An element of type []interface{} is spread as params of the function, other elements are passed as the only
+// param. Results are in the order of inputs. By default Map stops at the first error and returns it as *MapError,
+// the python processes still running are killed; with MapCollectErrors all inputs are invoked and failed ones get
+// the zero return value. When Map stops, at the first error or because ctx is done, the results of all inputs
+// which did not succeed, failed, killed or never started, are nil. Invocations go to the pool of fn or of its
+// runner when there is one.
+func Map(ctx context.Context, fn *WrapInfo, inputs interface{}, opts ...MapOption) ([]interface{}, error) {
+	v := reflect.ValueOf(inputs)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("map inputs is not a slice: %T", inputs)
+	}
+	if err := fn.ready(); err != nil {
+		return nil, err
+	}
+	c := mapConfig{concurrency: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+
+	results := make([]interface{}, v.Len())
+	mapCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	indexes := make(chan int)
+	lock := sync.Mutex{}
+	var errs MapErrors
+	wg := sync.WaitGroup{}
+	for n := 0; n < c.concurrency && n < v.Len(); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				value, err := fn.call(mapCtx, mapParams(v.Index(i).Interface()))
+				if err == nil || (c.collectErrors && mapCtx.Err() == nil) {
+					results[i] = value
+				}
+				if err != nil {
+					lock.Lock()
+					errs = append(errs, &MapError{Index: i, Err: err})
+					if !c.collectErrors {
+						cancel()
+					}
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < v.Len(); i++ {
+		select {
+		case indexes <- i:
+		case <-mapCtx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return results, contextError(err)
+	}
+	if len(errs) < 1 {
+		return results, nil
+	}
+	if !c.collectErrors {
+		return results, errs[0]
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+	return results, errs
+}
+
+// mapParams return params of function for an input element of Map
+func mapParams(input interface{}) []interface{} {
+	if params, ok := input.([]interface{}); ok {
+		return params
+	}
+	return []interface{}{input}
+}
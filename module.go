@@ -0,0 +1,82 @@
+package pfunc
+
+import "context"
+
+// ModuleInfo describe a python module imported by its dotted name with sys.path of the interpreter, like a
+// standard library module or an installed package, so no script file is needed, for example:
+//
+//	result := pfunc.Module("os.path").Call("join", "a", "b")
+//	s, err := pfunc.Module("json").Func("dumps").Return("").Params([]int{1, 2}).Do()
+type ModuleInfo struct {
+	name   string
+	runner *Runner
+	pool   *Pool
+}
+
+// Module describe a python module invoked by the default runner
+func Module(name string) *ModuleInfo {
+	return &ModuleInfo{name: name}
+}
+
+// Module describe a python module invoked by runner
+func (r *Runner) Module(name string) *ModuleInfo {
+	m := Module(name)
+	m.runner = r
+	return m
+}
+
+// Pool dispatch invocations to workers of the pool instead of the pool of runner
+func (m *ModuleInfo) Pool(p *Pool) *ModuleInfo {
+	m.pool = p
+	return m
+}
+
+// With make the module invoked by a copy of its runner with options applied, like entries of sys.path to
+// import it from, see WrapInfo.With
+func (m *ModuleInfo) With(options ...Option) *ModuleInfo {
+	runner := m.runner
+	if runner == nil {
+		runner = DefaultRunner()
+	}
+	m.runner = runner.With(options...)
+	return m
+}
+
+// Func wrap a function of module as go function, see pfunc.Func
+func (m *ModuleInfo) Func(funcName string) *WrapInfo {
+	w := Func("", funcName)
+	w.module = m.name
+	w.runner = m.runner
+	w.pool = m.pool
+	return w
+}
+
+// Class describe a class of module, see pfunc.Class
+func (m *ModuleInfo) Class(className string) *ClassInfo {
+	c := Class("", className)
+	c.module = m.name
+	c.runner = m.runner
+	c.pool = m.pool
+	return c
+}
+
+func (m *ModuleInfo) Call(funcName string, params ...interface{}) PResult {
+	return m.Invoke(funcName, params)
+}
+
+func (m *ModuleInfo) Invoke(funcName string, params []interface{}) PResult {
+	return m.InvokeContext(context.Background(), funcName, params)
+}
+
+func (m *ModuleInfo) CallContext(ctx context.Context, funcName string, params ...interface{}) PResult {
+	return m.InvokeContext(ctx, funcName, params)
+}
+
+// InvokeContext is like Invoke, but the python process is killed when the context is done
+func (m *ModuleInfo) InvokeContext(ctx context.Context, funcName string, params []interface{}) PResult {
+	runner := m.runner
+	if runner == nil {
+		runner = DefaultRunner()
+	}
+	return runner.dispatch(ctx, m.pool, invocation{module: m.name, funcName: funcName, params: params})
+}
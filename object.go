@@ -0,0 +1,167 @@
+package pfunc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrObjectReleased is returned by calls on a released python object handle
+var ErrObjectReleased = errors.New("python object is released")
+
+// ErrObjectInvalid is returned by calls on a python object handle whose worker exited, for example it crashed,
+// its invocation was canceled or the pool was closed
+var ErrObjectInvalid = errors.New("python object is invalid, its worker exited")
+
+// objectInfo describe an object kept by a worker
+type objectInfo struct {
+	ID     int64  `json:"id"`
+	Type   string `json:"type"`
+	Module string `json:"module"`
+}
+
+// PyObject is a handle of a python object kept alive in a pool worker, like a loaded model, a database connection
+// or a class instance, which may not be serializable to json. Calls on it are sent to the worker keeping it.
+//
+// A handle is reference counted: Retain adds a reference and Release drops one, the python object is dropped by
+// the worker when the last reference of all handles of it is released. A handle is invalid after its worker exits.
+type PyObject struct {
+	worker *worker
+	info   objectInfo
+	lock   sync.Mutex
+	refs   int
+}
+
+// Object invoke a python function in a worker and keep its result in the worker, a class can be invoked
+// to keep a new instance of it
+func (p *Pool) Object(ctx context.Context, scriptPath string, funcName string, params ...interface{}) (*PyObject, error) {
+	result, reply := p.invokeFunc(ctx, invocation{scriptPath: scriptPath, funcName: funcName, params: params}, true)
+	return newPyObject(result, reply)
+}
+
+// newPyObject make handle of the object in worker response, or return the error of invocation
+func newPyObject(result PResult, reply *workerReply) (*PyObject, error) {
+	if !result.NoError {
+		return nil, result.Exception
+	}
+	if reply.response.Object == nil {
+		return nil, fmt.Errorf("invoke python function error: python worker did not keep the result")
+	}
+	return &PyObject{worker: reply.worker, info: *reply.response.Object, refs: 1}, nil
+}
+
+// ID return the id of object in its worker, handles of the same python object have the same id
+func (o *PyObject) ID() int64 {
+	return o.info.ID
+}
+
+// Type return the python qualified type name of object with its module, like module.Outer.Inner, builtin types
+// has no module. Python 2 has no qualified names, nested classes are named without their outer class.
+func (o *PyObject) Type() string {
+	e := PythonError{Type: o.info.Type, Module: o.info.Module}
+	return e.QualifiedType()
+}
+
+// Call call a method of object and return its json result
+func (o *PyObject) Call(method string, params ...interface{}) PResult {
+	return o.CallContext(context.Background(), method, params...)
+}
+
+// CallContext is like Call, but the worker is killed when the context is done, which invalidates all its objects
+func (o *PyObject) CallContext(ctx context.Context, method string, params ...interface{}) PResult {
+	return o.invoke(ctx, poolRequest{Op: "call", Name: method, Args: params})
+}
+
+// CallKwargs call a method of object with keyword params
+func (o *PyObject) CallKwargs(ctx context.Context, method string, params []interface{}, kw map[string]interface{}) PResult {
+	return o.invoke(ctx, poolRequest{Op: "call", Name: method, Args: params, Kwargs: kw})
+}
+
+// CallObject call a method of object and keep its result in the worker
+func (o *PyObject) CallObject(ctx context.Context, method string, params ...interface{}) (*PyObject, error) {
+	return o.object(ctx, poolRequest{Op: "call", Name: method, Args: params, Keep: true})
+}
+
+// GetAttr return the json of an attribute of object
+func (o *PyObject) GetAttr(name string) PResult {
+	return o.GetAttrContext(context.Background(), name)
+}
+
+// GetAttrContext is like GetAttr, but the worker is killed when the context is done, which invalidates all its
+// objects
+func (o *PyObject) GetAttrContext(ctx context.Context, name string) PResult {
+	return o.invoke(ctx, poolRequest{Op: "getattr", Name: name})
+}
+
+// GetAttrObject keep an attribute of object in the worker and return its handle
+func (o *PyObject) GetAttrObject(ctx context.Context, name string) (*PyObject, error) {
+	return o.object(ctx, poolRequest{Op: "getattr", Name: name, Keep: true})
+}
+
+// Retain add a reference to the handle, it must be released once more
+func (o *PyObject) Retain() *PyObject {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.refs > 0 {
+		o.refs++
+	}
+	return o
+}
+
+// Release drop a reference of the handle, the python object is released in worker when the last reference of
+// it is dropped. Releasing a released handle returns ErrObjectReleased, releasing a handle whose worker exited
+// does nothing.
+func (o *PyObject) Release() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.refs < 1 {
+		return ErrObjectReleased
+	}
+	o.refs--
+	if o.refs > 0 || o.worker.exited() {
+		return nil
+	}
+	result := PResult{}
+	o.worker.invoke(context.Background(), poolRequest{Op: "release", Object: o.info.ID}, &result)
+	if !result.NoError {
+		return result.Exception
+	}
+	return nil
+}
+
+// invoke send request on object to its worker
+func (o *PyObject) invoke(ctx context.Context, request poolRequest) PResult {
+	result := PResult{}
+	if err := o.valid(); err != nil {
+		result.Exception = err
+		return result
+	}
+	request.Object = o.info.ID
+	o.worker.invoke(ctx, request, &result)
+	return result
+}
+
+// object send request on object to its worker and make handle of the kept result
+func (o *PyObject) object(ctx context.Context, request poolRequest) (*PyObject, error) {
+	if err := o.valid(); err != nil {
+		return nil, err
+	}
+	request.Object = o.info.ID
+	result := PResult{}
+	reply := o.worker.invoke(ctx, request, &result)
+	return newPyObject(result, reply)
+}
+
+// valid tells why the handle can not be used
+func (o *PyObject) valid() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.refs < 1 {
+		return ErrObjectReleased
+	}
+	if o.worker.exited() {
+		return ErrObjectInvalid
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package pfunc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// PayloadThresholdDefault is the default size in bytes of json params and results above which they are transferred
+// through temp files instead of the temp script and the result channel
+const PayloadThresholdDefault = 1 << 20
+
+// environment variable telling temp script the file to write a large result to and the threshold
+const resultFileEnv = "PFUNC_RESULT_FILE"
+
+// sent between result markers instead of a result written to the result file
+const resultFileMarker = "@pfunc_result_file"
+
+// pythonPayloadRuntime is the part of PythonRuntime reading params from payload files and writing large results to the result file
+const pythonPayloadRuntime string = `
+
+def pfunc_load_payload(ref, classes=()):
+    f = pfunc_open_channel(ref, "rb") if isinstance(ref, int) else open(ref, "rb")
+    try:
+        return pfunc_loads(f.read().decode("utf-8"), classes)
+    finally:
+        f.close()
+
+
+def pfunc_open_result_file():
+    config = os.environ.pop("PFUNC_RESULT_FILE", "")
+    if not config:
+        return None
+    config = json.loads(config)
+    ref = config["file"]
+    config["file"] = pfunc_open_channel(ref, "w") if isinstance(ref, int) else open(ref, "w")
+    return config
+
+
+def pfunc_dump_result(result, result_file):
+    data = pfunc_dumps(result)
+    if result_file is None or len(data) <= result_file["threshold"]:
+        return data
+    result_file["file"].write(data)
+    result_file["file"].close()
+    return "@pfunc_result_file"
+`
+
+// WithPayloadThreshold set the size in bytes of json params and results above which they are transferred through
+// temp files, the temp script holds a placeholder instead of the params. Less than 1 means never.
+func WithPayloadThreshold(n int) Option {
+	return func(r *Runner) {
+		r.payloadThreshold = n
+	}
+}
+
+// payloadFiles pass large json payloads of an invocation to python through temp files and receive a large result
+// through a temp file. On unix the files are removed once created and passed as file descriptors, so they are
+// released with the last descriptor, on windows they are passed by path and removed by close.
+type payloadFiles struct {
+	threshold int
+	firstFd   int
+	files     []*os.File
+	result    *os.File
+}
+
+func (r *Runner) newPayloadFiles() *payloadFiles {
+	return &payloadFiles{threshold: r.payloadThreshold}
+}
+
+// load return python expression loading json payload bs, which decodes a literal or reads a temp file,
+// classes are python classes of tagged objects in bs, see marshalClasses
+func (p *payloadFiles) load(bs []byte, classes []string) (string, error) {
+	if p.threshold < 1 || len(bs) <= p.threshold {
+		return fmt.Sprintf("pfunc_loads(%s, %s)", PythonStringLiteral(string(bs)), pythonList(classes)), nil
+	}
+	f, err := p.create()
+	if err != nil {
+		return "", fmt.Errorf("can not write params to temp file: %v", err)
+	}
+	p.files = append(p.files, f)
+	if _, err := f.Write(bs); err != nil {
+		return "", fmt.Errorf("can not write params to temp file: %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("can not write params to temp file: %v", err)
+	}
+	ref, _ := json.Marshal(p.reference(f, p.firstFd+len(p.files)-1))
+	return fmt.Sprintf("pfunc_load_payload(%s, %s)  # %v bytes of json params in a temp file",
+		ref, pythonList(classes), len(bs)), nil
+}
+
+// pythonList return python list literal of strs
+func pythonList(strs []string) string {
+	literals := make([]string, len(strs))
+	for i, s := range strs {
+		literals[i] = PythonStringLiteral(s)
+	}
+	return "[" + strings.Join(literals, ", ") + "]"
+}
+
+// attach pass files to command, and a result file when results may be large, after environment of command is set
+func (p *payloadFiles) attach(cmd *exec.Cmd, withResult bool) error {
+	if resultChannelSupported {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, p.files...)
+	}
+	if !withResult || p.threshold < 1 {
+		return nil
+	}
+	f, err := p.create()
+	if err != nil {
+		return fmt.Errorf("invoke python function error: can not create result file: %v", err)
+	}
+	p.result = f
+	if resultChannelSupported {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, f)
+	}
+	bs, _ := json.Marshal(map[string]interface{}{"file": p.reference(f, p.firstFd+len(p.files)), "threshold": p.threshold})
+	cmd.Env = append(cmd.Env, resultFileEnv+"="+string(bs))
+	return nil
+}
+
+// decode return the json sent between result markers, or the json written to result file
+func (p *payloadFiles) decode(section string) (string, error) {
+	if section != resultFileMarker || p.result == nil {
+		return section, nil
+	}
+	if _, err := p.result.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("invoke python function error: read result file error: %v", err)
+	}
+	var result json.RawMessage
+	if err := json.NewDecoder(p.result).Decode(&result); err != nil {
+		return "", fmt.Errorf("invoke python function error: read result file error: %v", err)
+	}
+	return string(result), nil
+}
+
+// close release all files
+func (p *payloadFiles) close() {
+	files := p.files
+	if p.result != nil {
+		files = append(files, p.result)
+	}
+	for _, f := range files {
+		f.Close()
+		if !resultChannelSupported {
+			os.Remove(f.Name())
+		}
+	}
+}
+
+// create make a temp file, which is removed at once when it can be passed as file descriptor
+func (p *payloadFiles) create() (*os.File, error) {
+	f, err := ioutil.TempFile("", "pfunc_payload_")
+	if err != nil {
+		return nil, err
+	}
+	if resultChannelSupported {
+		os.Remove(f.Name())
+	}
+	return f, nil
+}
+
+// reference return the file in python, which is descriptor fd or its path
+func (p *payloadFiles) reference(f *os.File, fd int) interface{} {
+	if !resultChannelSupported {
+		return f.Name()
+	}
+	return fd
+}
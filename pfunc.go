@@ -3,10 +3,8 @@ package pfunc
 import (
 	"bufio"
 	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,7 +15,9 @@ import (
 
 const PythonPath string = "PYTHONPATH"
 
-var pythonExecutable = "python"
+// major versions of python returned by ProbePythonVersion
+const Python2 = 2
+const Python3 = 3
 
 const InjectVarNamePrefixDefault = "pfunc_inject_"
 const ReturnValueStartDefault = "pfunc_return_start_"
@@ -25,74 +25,70 @@ const ReturnValueEndDefault = "pfunc_return_end_"
 const ExceptionStartDefault = "pfunc_exception_start_"
 const ExceptionEndDefault = "pfunc_exception_end_"
 
-var injectVarNamePrefix = InjectVarNamePrefixDefault
-var returnValueStart = ReturnValueStartDefault
-var returnValueEnd = ReturnValueEndDefault
-var exceptionStart = ExceptionStartDefault
-var exceptionEnd = ExceptionEndDefault
-
 func GetInjectVarNamePrefix() string {
-	return injectVarNamePrefix
+	return DefaultRunner().injectVarNamePrefix
 }
 
 func SetInjectVarNamePrefix(s string) {
-	injectVarNamePrefix = s
+	updateDefaultRunner(WithInjectVarNamePrefix(s))
 }
 
 func GetReturnValueStart() string {
-	return returnValueStart
+	return DefaultRunner().returnValueStart
 }
 
 func SetReturnValueStart(s string) {
-	returnValueStart = s
+	updateDefaultRunner(WithReturnValueMarkers(s, GetReturnValueEnd()))
 }
 
 func GetReturnValueEnd() string {
-	return returnValueEnd
+	return DefaultRunner().returnValueEnd
 }
 
 func SetReturnValueEnd(s string) {
-	returnValueEnd = s
+	updateDefaultRunner(WithReturnValueMarkers(GetReturnValueStart(), s))
 }
 
 func GetExceptionStart() string {
-	return exceptionStart
+	return DefaultRunner().exceptionStart
 }
 
 func SetExceptionStart(s string) {
-	exceptionStart = s
+	updateDefaultRunner(WithExceptionMarkers(s, GetExceptionEnd()))
 }
 
 func GetExceptionEnd() string {
-	return exceptionEnd
+	return DefaultRunner().exceptionEnd
 }
 
 func SetExceptionEnd(s string) {
-	exceptionEnd = s
+	updateDefaultRunner(WithExceptionMarkers(GetExceptionStart(), s))
 }
 
 func GetPythonExecutable() string {
-	return pythonExecutable
+	return DefaultRunner().executable
 }
 
 func SetPythonExecutable(s string) {
-	pythonExecutable = s
+	updateDefaultRunner(WithPythonExecutable(s))
+}
+
+func GetPool() *Pool {
+	return DefaultRunner().pool
+}
+
+// SetPool dispatch invocations of Invoke, Call and WrapInfo.Do to workers of the pool,
+// nil means starting a python process for every invocation
+func SetPool(p *Pool) {
+	updateDefaultRunner(WithPool(p))
 }
 
 func AddTemplateElementNamesPrefix(s string) {
-	SetInjectVarNamePrefix(s + InjectVarNamePrefixDefault)
-	SetReturnValueStart(s + ReturnValueStartDefault)
-	SetReturnValueEnd(s + ReturnValueEndDefault)
-	SetExceptionStart(s + ExceptionStartDefault)
-	SetExceptionEnd(s + ExceptionEndDefault)
+	updateDefaultRunner(WithTemplateElementNamesPrefix(s))
 }
 
 func ResetTemplateElementNames() {
-	SetInjectVarNamePrefix(InjectVarNamePrefixDefault)
-	SetReturnValueStart(ReturnValueStartDefault)
-	SetReturnValueEnd(ReturnValueEndDefault)
-	SetExceptionStart(ExceptionStartDefault)
-	SetExceptionEnd(ExceptionEndDefault)
+	updateDefaultRunner(WithTemplateElementNamesPrefix(""))
 }
 
 const PResultToString = `
@@ -109,22 +105,32 @@ python function result:
         %v
 `
 
-const Python2ScriptTemplate string = `
-from %s import %s
+// PythonScriptTemplate is the temp script invoking a python function, it runs on both python 2 and python 3
+const PythonScriptTemplate string = `
+import os
+import sys
 import traceback
 import json
+` + PythonRuntime + `
+pfunc_channel = pfunc_open_channel(%d, "w")
+pfunc_result_file = pfunc_open_result_file()
+pfunc_install_runtime(*pfunc_callback_channels())
+pfunc_restrict()
+pfunc_extend_sys_path()
+pfunc_configure_encoding()
 try:
-    null = None
+    from %s import %s
 %s
     result = %s
-    print '%s{}%s'.format(json.dumps(result))
-except Exception, e:
-    msg = traceback.format_exc()
-    print "%s",
-    print msg,
-    print "%s",
+    pfunc_send(pfunc_channel, "result", pfunc_dump_result(result, pfunc_result_file), '%s', '%s')
+except Exception as e:
+    pfunc_send(pfunc_channel, "exception", json.dumps(pfunc_exception(e, sys.exc_info()[2])), '%s', '%s')
+pfunc_channel.flush()
 `
 
+// Deprecated: Python2ScriptTemplate is PythonScriptTemplate, which also runs on python 2
+const Python2ScriptTemplate = PythonScriptTemplate
+
 // invoke result struct
 type PResult struct {
 	NoError            bool
@@ -133,6 +139,7 @@ type PResult struct {
 	TempScript         string
 	Output             string
 	PythonPath         string
+	Process            ProcessConfig
 }
 
 type WrapInfo struct {
@@ -145,12 +152,24 @@ type WrapInfo struct {
 	paramDefaultValues []interface{}
 	Keywords           map[string]interface{}
 	wrapError          []error
+	pool               *Pool
+	runner             *Runner
+	module             string
+	method             string
+	initParams         []interface{}
+	initKw             map[string]interface{}
+	cache              Cache
 }
 
+// Inspect describe result for humans, a return value larger than PayloadThresholdDefault is shown as a placeholder
 func (pr PResult) Inspect() string {
+	value := pr.JsonRepresentation
+	if len(value) > PayloadThresholdDefault {
+		value = fmt.Sprintf("<%v bytes of json>", len(value))
+	}
 	return fmt.Sprintf(PResultToString,
 		Select(pr.NoError, "success", "fail").(string),
-		TabString(pr.JsonRepresentation, 8),
+		TabString(value, 8),
 		TabString(pr.Exception.Error(), 8),
 		TabString(pr.TempScript, 8),
 		pr.PythonPath)
@@ -167,7 +186,7 @@ func (pr PResult) MustInt() int {
 
 func (pr PResult) String() (string, error) {
 	var str string
-	err := json.Unmarshal([]byte(pr.JsonRepresentation), &str)
+	err := Unmarshal([]byte(pr.JsonRepresentation), &str)
 	return str, err
 }
 
@@ -178,7 +197,7 @@ func (pr PResult) MustString() string {
 
 func (pr PResult) Float() (float32, error) {
 	var f float32
-	err := json.Unmarshal([]byte(pr.JsonRepresentation), &f)
+	err := Unmarshal([]byte(pr.JsonRepresentation), &f)
 	return f, err
 }
 
@@ -187,6 +206,11 @@ func (pr PResult) MustFloat() float32 {
 	return f
 }
 
+// Decode decode return value into v by Unmarshal
+func (pr PResult) Decode(v interface{}) error {
+	return Unmarshal([]byte(pr.JsonRepresentation), v)
+}
+
 func Func(scriptPath string, funcName string) *WrapInfo {
 	wrapInfo := &WrapInfo{}
 	wrapInfo.scriptPath = scriptPath
@@ -223,6 +247,12 @@ func (w *WrapInfo) ParamDefaults(interfaces ...interface{}) *WrapInfo {
 	return w
 }
 
+// Pool dispatch the invocation to workers of the pool instead of the pool of runner
+func (w *WrapInfo) Pool(p *Pool) *WrapInfo {
+	w.pool = p
+	return w
+}
+
 func (w *WrapInfo) VarArgs(varargs interface{}) *WrapInfo {
 	t := reflect.TypeOf(varargs)
 	k := t.Kind()
@@ -240,28 +270,85 @@ func (w *WrapInfo) VarArgs(varargs interface{}) *WrapInfo {
 	return w
 }
 
+// invocation describe the invocation of wrapped function with params
+func (w *WrapInfo) invocation(params []interface{}) invocation {
+	return invocation{
+		module:     w.module,
+		scriptPath: w.scriptPath,
+		funcName:   w.funcName,
+		params:     params,
+		kw:         w.Keywords,
+		method:     w.method,
+		initParams: w.initParams,
+		initKw:     w.initKw,
+	}
+}
+
+// Do invoke the wrapped function with args, or with params set by Params when no args are given
 func (w *WrapInfo) Do(interfaces ...interface{}) (interface{}, error) {
+	return w.DoContext(context.Background(), interfaces...)
+}
+
+// DoContext is like Do, but the python process is killed when the context is done,
+// then ErrTimeout or ErrCanceled is returned
+func (w *WrapInfo) DoContext(ctx context.Context, interfaces ...interface{}) (interface{}, error) {
+	if err := w.ready(); err != nil {
+		return nil, err
+	}
+	return w.call(ctx, w.params(interfaces))
+}
+
+// params return args given to an invocation, or params set by Params when no args are given
+func (w *WrapInfo) params(args []interface{}) []interface{} {
+	if len(args) > 0 {
+		return args
+	}
+	return w.paramValues
+}
 
+// ready tells why the wrapped function can not be invoked
+func (w *WrapInfo) ready() error {
 	if w.returnType == nil {
-		return nil, fmt.Errorf("return type is not set")
+		return fmt.Errorf("return type is not set")
 	}
 
 	if len(w.wrapError) > 0 {
-		return nil, w.wrapError[0]
+		return w.wrapError[0]
 	}
+	return nil
+}
 
-	if len(w.paramDefaultValues) > 0 {
-		for i, d := range w.paramDefaultValues {
-			if i >= len(w.paramValues) {
-				w.paramValues = append(w.paramValues, d)
-			}
+// call invoke the wrapped function with params and default values of missing params, it does not change w,
+// so it is safe to call it from many goroutines
+func (w *WrapInfo) call(ctx context.Context, params []interface{}) (interface{}, error) {
+	runner := w.runner
+	if runner == nil {
+		runner = DefaultRunner()
+	}
+
+	c := w.cache
+	if c == nil {
+		c = runner.cache
+	}
+	return w.decode(runner.dispatchCache(ctx, w.pool, c, w.invocation(w.withDefaults(params))))
+}
+
+// withDefaults return params with default values appended for missing params
+func (w *WrapInfo) withDefaults(params []interface{}) []interface{} {
+	params = append([]interface{}(nil), params...)
+	for i, d := range w.paramDefaultValues {
+		if i >= len(params) {
+			params = append(params, d)
 		}
 	}
+	return params
+}
 
-	r := doInvoke(w.scriptPath, w.funcName, w.paramValues, w.Keywords)
+// decode return value of invocation result to the return type, or return the zero return value and exception
+func (w *WrapInfo) decode(r PResult) (interface{}, error) {
 	if r.NoError {
 		i := reflect.New(w.returnType).Interface()
-		err := json.Unmarshal([]byte(r.JsonRepresentation), i)
+		err := Unmarshal([]byte(r.JsonRepresentation), i)
 		if err != nil {
 			return w.returnValue, err
 		}
@@ -278,123 +365,177 @@ func Call(scriptPath string, funcName string, params ...interface{}) PResult {
 }
 
 func Invoke(scriptPath string, funcName string, params []interface{}) PResult {
-	return doInvoke(scriptPath, funcName, params, nil)
+	return DefaultRunner().Invoke(scriptPath, funcName, params)
+}
+
+func CallContext(ctx context.Context, scriptPath string, funcName string, params ...interface{}) PResult {
+	return DefaultRunner().InvokeContext(ctx, scriptPath, funcName, params)
+}
+
+// InvokeContext is like Invoke, but the python process and its children are killed when the context is done,
+// the result holds ErrTimeout or ErrCanceled as exception and the output captured before
+func InvokeContext(ctx context.Context, scriptPath string, funcName string, params []interface{}) PResult {
+	return DefaultRunner().InvokeContext(ctx, scriptPath, funcName, params)
 }
 
-func doInvoke(scriptPath string, funcName string, params []interface{}, kw map[string]interface{}) PResult {
+func (r *Runner) doInvoke(ctx context.Context, inv invocation) PResult {
 	result := PResult{}
-	if _, err := os.Stat(scriptPath); os.IsNotExist(err) {
-		result.Exception = fmt.Errorf("invoke python function error: python script not exists: %v: %v", scriptPath, err)
+	files := r.newPayloadFiles()
+	defer files.close()
+	message, noResult, ok := r.runScript(ctx, inv, &result, files, func(channelFd int) (string, string, error) {
+		return r.generateTempScript(PythonScriptTemplate, channelFd, inv, files)
+	})
+	if !ok {
 		return result
 	}
 
-	tempScript, appendPythonPath, err := generateTempScript(scriptPath, funcName, params, kw)
+	value, exception := r.parseMessage(message)
+	section, err := files.decode(value)
 	if err != nil {
-		result.Exception = fmt.Errorf("invoke python function error: generate temp script error: %v", err)
+		result.Exception = err
 		return result
 	}
-	result.TempScript = tempScript
+	result.JsonRepresentation = section
+	result.Exception = exceptionError(exception)
 
-	cmd := exec.Command(pythonExecutable)
+	if len(result.JsonRepresentation) < 1 && len(result.Exception.Error()) < 1 {
+		result.Exception = noResult
+	}
+	result.Exception = r.limitError(result.Exception)
 
-	cmd.Env = os.Environ()
-	if len(appendPythonPath) > 0 {
-		AddEnv(&cmd.Env, PythonPath, appendPythonPath)
+	if len(result.Exception.Error()) < 1 {
+		result.NoError = true
 	}
-	result.PythonPath, _ = GetEnv(&cmd.Env, PythonPath)
 
-	sin, err := cmd.StdinPipe()
-	if err != nil {
-		result.Exception = fmt.Errorf("invoke python function error: pipe stdin of python error: %v", err)
-		return result
+	return result
+}
+
+// runScript run the temp script made by generate in a new python process and return what python sent through
+// result channel, and the error to report when python sent nothing. Temp script, python path and output are
+// filled to result, or the exception of result is set and false is returned when it failed to run.
+func (r *Runner) runScript(ctx context.Context, inv invocation, result *PResult, files *payloadFiles, generate func(channelFd int) (string, string, error)) (string, error, bool) {
+	if err := ctx.Err(); err != nil {
+		result.Exception = contextError(err)
+		return "", nil, false
 	}
 
-	sout, err := cmd.StdoutPipe()
-	if err != nil {
-		result.Exception = fmt.Errorf("invoke python function error: pipe stdout of python error: %v", err)
-		return result
+	if err := inv.check(); err != nil {
+		result.Exception = err
+		return "", nil, false
 	}
-	defer sout.Close()
 
-	serr, err := cmd.StderrPipe()
+	cmd := exec.Command(r.executable)
+	setProcessGroup(cmd)
+
+	channel, err := openResultChannel(cmd)
 	if err != nil {
-		result.Exception = fmt.Errorf("invoke python function error: pipe stderr of python error: %v", err)
-		return result
+		result.Exception = fmt.Errorf("invoke python function error: open result channel error: %v", err)
+		return "", nil, false
 	}
-	defer serr.Close()
 
-	err = cmd.Start()
+	files.firstFd = extraFilesFd + len(cmd.ExtraFiles)
+	tempScript, appendPythonPath, err := generate(channel.fd)
 	if err != nil {
-		result.Exception = fmt.Errorf("invoke python function error: %v", err)
-		return result
+		channel.close()
+		result.Exception = fmt.Errorf("invoke python function error: generate temp script error: %v", err)
+		return "", nil, false
 	}
+	result.TempScript = tempScript
 
-	_, err = sin.Write([]byte(tempScript))
-	if err != nil {
-		result.Exception = fmt.Errorf("invoke python function error: input python script to python error: %v", err)
-		return result
+	r.setupCommand(cmd, appendPythonPath)
+	result.PythonPath, _ = GetEnv(&cmd.Env, PythonPath)
+	result.Process = r.processConfig(cmd.Env)
+	if err := files.attach(cmd, true); err != nil {
+		channel.close()
+		result.Exception = err
+		return "", nil, false
+	}
+	if err := r.restrict(cmd); err != nil {
+		channel.close()
+		result.Exception = err
+		return "", nil, false
 	}
-	sin.Close()
 
-	bs, err := ioutil.ReadAll(sout)
+	callback, err := openCallbackChannel(cmd, r.callbacks)
 	if err != nil {
-		result.Exception = fmt.Errorf("invoke python function error: get python output error: %v", err)
-		return result
+		channel.close()
+		result.Exception = fmt.Errorf("invoke python function error: open callback channel error: %v", err)
+		return "", nil, false
 	}
 
-	be, err := ioutil.ReadAll(serr)
+	sout := bytes.Buffer{}
+	serr := bytes.Buffer{}
+	limited := newLimitedOutput(cmd, r.limits.OutputSize)
+	cmd.Stdin = strings.NewReader(tempScript)
+	cmd.Stdout = limited.writer(&sout)
+	cmd.Stderr = limited.writer(&serr)
+
+	err = r.start(cmd)
 	if err != nil {
-		result.Exception = fmt.Errorf("invoke python function error: get python error output error: %v", err)
-		return result
+		channel.close()
+		callback.close()
+		result.Exception = fmt.Errorf("invoke python function error: %v", err)
+		return "", nil, false
 	}
+	channel.started()
+	callback.started(ctx)
 
-	output := string(bs)
-	errorOutput := string(be)
+	err = waitContext(ctx, cmd)
+	callback.wait()
 
+	output := sout.String()
+	errorOutput := serr.String()
+	message := channel.message(output)
 	result.Output = output + errorOutput
-	result.JsonRepresentation = SubStringBetween(output, returnValueStart, returnValueEnd)
-	result.Exception = errors.New(SubStringBetween(output, exceptionStart, exceptionEnd))
 
-	if len(errorOutput) > 0 {
-		result.Exception = errors.New(errorOutput)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		result.Exception = contextError(ctxErr)
+		return "", nil, false
 	}
-
-	if len(result.Exception.Error()) < 1 {
-		result.NoError = true
+	if limitErr := r.exceeded(cmd, limited); limitErr != nil {
+		result.Exception = limitErr
+		return "", nil, false
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		result.Exception = fmt.Errorf("invoke python function error: %v", err)
+		return "", nil, false
 	}
 
-	return result
+	// python may exit before sending anything, for example a syntax error or os._exit()
+	var noResult error
+	if e := ParseTraceback(errorOutput); e != nil {
+		noResult = e
+	} else {
+		noResult = fmt.Errorf("invoke python function error: python exited without result: %v\n%v", cmd.ProcessState, errorOutput)
+	}
+	return message, noResult, true
 }
 
-// generate temp script to send to python interpreter
-func generateTempScript(scriptPath string, funcName string, params []interface{}, kw map[string]interface{}) (string, string, error) {
+// generate temp script from template to send to python interpreter
+func (r *Runner) generateTempScript(template string, channelFd int, inv invocation, files *payloadFiles) (string, string, error) {
 	script := bytes.Buffer{}
-	var appendPythonPath string
-
-	from, err := getRelativeImportPath(scriptPath)
-	if err != nil {
-		from, appendPythonPath = getAbsoluteImportPath(scriptPath)
-	}
+	from, appendPythonPath := inv.importFrom(r.importPaths())
 
-	vars, err := injectScriptVars(params, kw)
+	vars, err := r.injectScriptVars(inv, files)
 	if err != nil {
 		return "", appendPythonPath, err
 	}
 
-	invoker, err := injectScriptFuncInvoke(funcName, params, kw)
+	invoker, err := r.injectScriptFuncInvoke(inv)
 	if err != nil {
 		return "", appendPythonPath, err
 	}
 
-	str := fmt.Sprintf(Python2ScriptTemplate,
+	str := fmt.Sprintf(template,
+		channelFd,
 		from,
-		funcName,
+		inv.importName(),
 		TabString(vars, 4),
 		invoker,
-		returnValueStart,
-		returnValueEnd,
-		exceptionStart,
-		exceptionEnd)
+		r.returnValueStart,
+		r.returnValueEnd,
+		r.exceptionStart,
+		r.exceptionEnd)
 
 	script.WriteString(str)
 	return script.String(), appendPythonPath, nil
@@ -411,9 +552,8 @@ func GetPythonPaths() []string {
 	}
 }
 
-// getRelativeImportPath get import path by relative path from PYTHONPATH to target script
-func getRelativeImportPath(scriptPath string) (string, error) {
-	ps := GetPythonPaths()
+// getRelativeImportPath get import path by relative path from PYTHONPATH entries to target script
+func getRelativeImportPath(ps []string, scriptPath string) (string, error) {
 	for _, p := range ps {
 		if b, rel := InPath(p, scriptPath); b {
 			rel = strings.Replace(rel, string(os.PathSeparator)+"..", ".", -1)
@@ -437,57 +577,74 @@ func getAbsoluteImportPath(scriptPath string) (string, string) {
 	return b, d
 }
 
-// injectScriptFuncInvoke generate script section to invoke and pass value to an python function,
+// injectScriptFuncInvoke generate script section to invoke an python function with the decoded payload,
 // for example:
-//   func1(var1, var2)
-func injectScriptFuncInvoke(funcName string, params []interface{}, kw map[string]interface{}) (string, error) {
-	var args []string
-	for i, _ := range params {
-		varName := fmt.Sprintf("%s%d", injectVarNamePrefix, i)
-		args = append(args, varName)
-	}
-	if kw != nil {
-		for k, _ := range kw {
-			varNameValue := fmt.Sprintf("%s = %s_%s", k, injectVarNamePrefix, k)
-			args = append(args, varNameValue)
-		}
+//   func1(*pfunc_inject_payload["args"], **pfunc_inject_payload["kwargs"])
+// or a method of a new instance of class:
+//   Model(*pfunc_inject_payload.get("init_args", []), **pfunc_inject_payload.get("init_kwargs", {})).predict(...)
+func (r *Runner) injectScriptFuncInvoke(inv invocation) (string, error) {
+	payload := r.payloadVarName()
+	target := inv.funcName
+	if len(inv.method) > 0 {
+		target = fmt.Sprintf(`%s(*%s.get("init_args", []), **%s.get("init_kwargs", {})).%s`, inv.funcName, payload, payload, inv.method)
+	}
+	return fmt.Sprintf(`%s(*%s["args"], **%s["kwargs"])`, target, payload, payload), nil
+}
+
+// injectScriptVars generate script section to decode all params from one json payload. for example:
+//   pfunc_inject_payload = pfunc_loads(u'{"args": [1, 2], "kwargs": {}}', [])
+func (r *Runner) injectScriptVars(inv invocation, files *payloadFiles) (string, error) {
+	bs, classes, err := marshalPayload(inv.payload())
+	if err != nil {
+		return "", err
 	}
-	return fmt.Sprintf("%s(%s)", funcName, strings.Join(args, ", ")), nil
-}
-
-// injectScriptVars generate script section to define some variable. for example:
-//   var1 = xxx
-//   var2 = yyy
-func injectScriptVars(params []interface{}, kw map[string]interface{}) (string, error) {
-	if len(params) < 1 {
-		return "", nil
+	load, err := files.load(bs, classes)
+	if err != nil {
+		return "", err
 	}
+	return fmt.Sprintf("%s = %s\n", r.payloadVarName(), load), nil
+}
 
-	script := bytes.Buffer{}
-
-	for i, param := range params {
-		varName := fmt.Sprintf("%s%d", injectVarNamePrefix, i)
-		bs, err := json.Marshal(param)
-		if err != nil {
-			return "", fmt.Errorf("can not serialize param to json value: %v", err)
-		}
-		varValue := string(bs)
-		script.WriteString(fmt.Sprintf("%s = %s\n", varName, varValue))
-	}
+func (r *Runner) payloadVarName() string {
+	return r.injectVarNamePrefix + "payload"
+}
 
-	if kw != nil {
-		for k, v := range kw {
-			varName := fmt.Sprintf("%s_%s", injectVarNamePrefix, k)
-			bs, err := json.Marshal(v)
-			if err != nil {
-				return "", fmt.Errorf("can not serialize keyword param to json value: %v", err)
-			}
-			varValue := string(bs)
-			script.WriteString(fmt.Sprintf("%s = %s\n", varName, varValue))
+// marshalPayload serialize params and keyword params to one json payload, and return python classes of objects in it
+func marshalPayload(p payload) ([]byte, []string, error) {
+	bs, classes, err := marshalClasses(p)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not serialize params to json value: %v", err)
+	}
+	return bs, classes, nil
+}
+
+// PythonStringLiteral quote string as an ascii only python unicode literal, which is valid in python 2 and python 3
+func PythonStringLiteral(str string) string {
+	literal := bytes.NewBufferString("u'")
+	for _, c := range str {
+		switch {
+		case c == '\\':
+			literal.WriteString(`\\`)
+		case c == '\'':
+			literal.WriteString(`\'`)
+		case c == '\n':
+			literal.WriteString(`\n`)
+		case c == '\r':
+			literal.WriteString(`\r`)
+		case c == '\t':
+			literal.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f:
+			literal.WriteString(fmt.Sprintf(`\x%02x`, c))
+		case c < 0x7f:
+			literal.WriteRune(c)
+		case c <= 0xffff:
+			literal.WriteString(fmt.Sprintf(`\u%04x`, c))
+		default:
+			literal.WriteString(fmt.Sprintf(`\U%08x`, c))
 		}
 	}
-
-	return script.String(), nil
+	literal.WriteString("'")
+	return literal.String()
 }
 
 // SubStringBetween return substring between inputted prefix and suffix
@@ -506,6 +663,7 @@ func TabString(str string, tabSize int) string {
 
 	buffer := bytes.NewBufferString(str)
 	scanner := bufio.NewScanner(buffer)
+	scanner.Buffer(nil, len(str)+1)
 
 	first := true
 	for scanner.Scan() {
@@ -534,6 +692,7 @@ func Select(tf bool, a interface{}, b interface{}) interface{} {
 // FindLine return first line in string which contains inputted substring
 func FindLine(str string, subs ...string) string {
 	scanner := bufio.NewScanner(bytes.NewBufferString(str))
+	scanner.Buffer(nil, len(str)+1)
 outer:
 	for scanner.Scan() {
 		for _, sub := range subs {
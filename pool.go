@@ -0,0 +1,816 @@
+package pfunc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by invocations on a closed pool
+var ErrPoolClosed = errors.New("python worker pool is closed")
+
+// how long Close waits for a worker to exit after its stdin is closed
+const workerStopTimeout = 3 * time.Second
+
+// max bytes of worker stdout and stderr kept to report a crash
+const workerOutputLimit = 64 * 1024
+
+// PythonWorkerScript is run by every pool worker, it reads one json request per line from the request channel,
+// invokes the function and writes one json response per line to the response channel. File descriptors of
+// channels are passed as arguments, -1 means stdin and stdout. It is written to run on both python 2 and python 3.
+const PythonWorkerScript string = `
+import os
+import sys
+import json
+import importlib
+import itertools
+import hashlib
+import tempfile
+import traceback
+try:
+    from StringIO import StringIO
+except ImportError:
+    from io import StringIO
+` + PythonRuntime + `
+
+def pfunc_serve(fd_in, fd_out):
+    channel_in = pfunc_open_channel(fd_in, "r")
+    channel_out = pfunc_open_channel(fd_out, "w")
+    runtime = pfunc_install_runtime(channel_out, channel_in)
+    output = pfunc_output(channel_out, runtime._lock)
+    stdout = sys.stdout
+    stderr = sys.stderr
+    scripts = {}
+    objects = {}
+    handles = {}
+    counter = itertools.count(1)
+    while True:
+        line = channel_in.readline()
+        if not line:
+            break
+        request = json.loads(line)
+        if request.get("payload"):
+            request = pfunc_read_request(request["payload"])
+        sys.stdout = output
+        sys.stderr = output
+        try:
+            try:
+                op = request.get("op", "invoke")
+                # params are decoded after sys.path is set, as they may be instances of classes of the script
+                if request.get("path"):
+                    pfunc_prefer_path(request["path"])
+                classes = request.get("classes", [])
+                if op == "invoke":
+                    result = pfunc_invoke(pfunc_import_target(scripts, request), request, classes)
+                elif op == "batch":
+                    target = pfunc_import_target(scripts, request)
+                    result = [pfunc_batch_call(target, request, call, classes) for call in request["calls"]]
+                elif op == "release":
+                    result = pfunc_release_object(objects, handles, request["object"])
+                else:
+                    target = pfunc_get_object(objects, request["object"])
+                    result = pfunc_resolve(target, request["name"])
+                    if op == "call":
+                        args = pfunc_revive(request.get("args", []), classes)
+                        kwargs = pfunc_revive(request.get("kwargs", {}), classes)
+                        result = result(*args, **kwargs)
+                if request.get("keep"):
+                    response = {"ok": True, "object": pfunc_keep_object(objects, handles, counter, result)}
+                else:
+                    response = {"ok": True, "result": result}
+            except Exception as e:
+                response = {"ok": False, "exception": pfunc_exception(e, sys.exc_info()[2])}
+        finally:
+            sys.stdout = stdout
+            sys.stderr = stderr
+        try:
+            data = pfunc_dumps(response)
+        except Exception as e:
+            data = pfunc_dumps({"ok": False, "exception": pfunc_exception(e, sys.exc_info()[2])})
+        if 0 < request.get("threshold", 0) < len(data):
+            data = pfunc_write_response(data)
+        runtime._lock.acquire()
+        try:
+            channel_out.write(data + "\n")
+            channel_out.flush()
+        finally:
+            runtime._lock.release()
+
+
+# stdout and stderr of requests, what is written is sent to go at once, so it is kept when the worker is killed
+class pfunc_output(object):
+    encoding = "utf-8"
+
+    def __init__(self, channel, lock):
+        self.channel = channel
+        self.lock = lock
+
+    def write(self, text):
+        if not text:
+            return
+        if not isinstance(text, type(u"")):
+            text = text.decode("utf-8", "replace")
+        self.lock.acquire()
+        try:
+            self.channel.write(json.dumps({"write": text}) + "\n")
+            self.channel.flush()
+        finally:
+            self.lock.release()
+
+    def writelines(self, lines):
+        for line in lines:
+            self.write(line)
+
+    def flush(self):
+        pass
+
+    def isatty(self):
+        return False
+
+
+def pfunc_import_target(scripts, request):
+    if request.get("script"):
+        module = pfunc_load_script(scripts, request["script"], request["module"])
+    else:
+        module = importlib.import_module(request["module"])
+    return pfunc_resolve(module, request["func"])
+
+
+# call target with params of payload, or the method of an instance made with params of class constructor
+def pfunc_invoke(target, payload, classes):
+    if payload.get("method"):
+        init_args = pfunc_revive(payload.get("init_args", []), classes)
+        init_kwargs = pfunc_revive(payload.get("init_kwargs", {}), classes)
+        target = pfunc_resolve(target(*init_args, **init_kwargs), payload["method"])
+    args = pfunc_revive(payload.get("args", []), classes)
+    kwargs = pfunc_revive(payload.get("kwargs", {}), classes)
+    return target(*args, **kwargs)
+
+
+# one call of a batch, its result is encoded and its output captured apart, so a failed call does not abort others
+def pfunc_batch_call(target, request, call, classes):
+    call["method"] = request.get("method")
+    output = StringIO()
+    stdout, stderr = sys.stdout, sys.stderr
+    sys.stdout = sys.stderr = output
+    try:
+        try:
+            result = {"ok": True, "result": pfunc_dumps(pfunc_invoke(target, call, classes))}
+        except Exception as e:
+            result = {"ok": False, "exception": pfunc_exception(e, sys.exc_info()[2])}
+    finally:
+        sys.stdout, sys.stderr = stdout, stderr
+    result["output"] = output.getvalue()
+    return result
+
+
+def pfunc_read_request(path):
+    f = open(path, "rb")
+    try:
+        return json.loads(f.read().decode("utf-8"))
+    finally:
+        f.close()
+
+
+# write a large response to a temp file removed by go, and return the line naming it
+def pfunc_write_response(data):
+    fd, path = tempfile.mkstemp(prefix="pfunc_payload_")
+    f = os.fdopen(fd, "wb")
+    try:
+        f.write(data.encode("utf-8"))
+    finally:
+        f.close()
+    return json.dumps({"payload": path})
+
+
+def pfunc_prefer_path(path):
+    if path in sys.path:
+        sys.path.remove(path)
+    sys.path.insert(0, path)
+
+
+# load script by its file, scripts of the same name in other directories or named like a standard module
+# are loaded under another module name. A loaded script is loaded again when its content changes.
+def pfunc_load_script(scripts, path, name):
+    digest = pfunc_file_digest(path)
+    if path in scripts:
+        module, loaded_digest = scripts[path]
+        if loaded_digest == digest:
+            return module
+        module = pfunc_import_file(module.__name__, path)
+        scripts[path] = (module, digest)
+        return module
+    loaded = sys.modules.get(name)
+    if loaded is not None and pfunc_same_file(getattr(loaded, "__file__", None), path):
+        module = loaded
+    else:
+        if loaded is not None:
+            name = "pfunc_script_{0}_{1}".format(len(scripts), name)
+        module = pfunc_import_file(name, path)
+    scripts[path] = (module, digest)
+    return module
+
+
+def pfunc_file_digest(path):
+    f = open(path, "rb")
+    try:
+        return hashlib.sha256(f.read()).hexdigest()
+    finally:
+        f.close()
+
+
+def pfunc_same_file(a, b):
+    return a is not None and os.path.splitext(os.path.abspath(a))[0] == os.path.splitext(os.path.abspath(b))[0]
+
+
+def pfunc_import_file(name, path):
+    try:
+        import importlib.util
+    except ImportError:
+        import imp
+        return imp.load_source(name, path)
+    spec = importlib.util.spec_from_file_location(name, path)
+    module = importlib.util.module_from_spec(spec)
+    sys.modules[name] = module
+    try:
+        spec.loader.exec_module(module)
+    except BaseException:
+        del sys.modules[name]
+        raise
+    return module
+
+
+def pfunc_keep_object(objects, handles, counter, obj):
+    handle = handles.get(id(obj))
+    if handle is None:
+        handle = next(counter)
+        handles[id(obj)] = handle
+        objects[handle] = [obj, 0]
+    objects[handle][1] += 1
+    return {"id": handle, "type": pfunc_qualname(type(obj)), "module": type(obj).__module__}
+
+
+def pfunc_get_object(objects, handle):
+    if handle not in objects:
+        raise LookupError("python object is released: {0}".format(handle))
+    return objects[handle][0]
+
+
+def pfunc_release_object(objects, handles, handle):
+    entry = objects.get(handle)
+    if entry is not None:
+        entry[1] -= 1
+        if entry[1] < 1:
+            del objects[handle]
+            del handles[id(entry[0])]
+
+
+pfunc_extend_sys_path()
+pfunc_configure_encoding()
+pfunc_serve(int(sys.argv[1]), int(sys.argv[2]))
+`
+
+// Pool keeps some long-lived python workers, every worker imports modules once and serves invocations
+// one by one, so the interpreter startup and module import cost is paid once per worker instead of
+// once per call.
+type Pool struct {
+	runner    *Runner
+	size      int
+	idle      chan *worker
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// worker is one python process running PythonWorkerScript, lock serializes round trips of invocations
+// and calls on objects kept by it
+type worker struct {
+	lock      sync.Mutex
+	cmd       *exec.Cmd
+	requests  io.WriteCloser
+	responses *bufio.Reader
+	closers   []io.Closer
+	output    *tailBuffer
+	callbacks *callbacks
+	threshold int
+	done      chan struct{}
+}
+
+// poolRequest is the json request line sent to a worker, op is "invoke" by default, "batch" to invoke the function
+// with every payload of calls, or "call", "getattr" and "release" on a kept object. Keep asks worker to keep the
+// result and respond with its handle.
+type poolRequest struct {
+	Op     string                 `json:"op,omitempty"`
+	Path   string                 `json:"path,omitempty"`
+	Script string                 `json:"script,omitempty"`
+	Module string                 `json:"module,omitempty"`
+	Func   string                 `json:"func,omitempty"`
+	Object int64                  `json:"object,omitempty"`
+	Name   string                 `json:"name,omitempty"`
+	Args   []interface{}          `json:"args"`
+	Kwargs map[string]interface{} `json:"kwargs,omitempty"`
+	Keep   bool                   `json:"keep,omitempty"`
+
+	Method     string                 `json:"method,omitempty"`
+	InitArgs   []interface{}          `json:"init_args,omitempty"`
+	InitKwargs map[string]interface{} `json:"init_kwargs,omitempty"`
+
+	Calls []payload `json:"calls,omitempty"`
+
+	// python classes of objects in params, set by invoke
+	Classes []string `json:"classes,omitempty"`
+	// size of response above which worker writes it to a temp file, set by invoke
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// poolResponse is the json response line received from a worker
+type poolResponse struct {
+	Ok        bool            `json:"ok"`
+	Result    json.RawMessage `json:"result"`
+	Object    *objectInfo     `json:"object"`
+	Exception *PythonError    `json:"exception"`
+}
+
+// NewPool start size python workers configured by the default runner
+func NewPool(size int) (*Pool, error) {
+	return DefaultRunner().NewPool(size)
+}
+
+func newPool(r *Runner, size int) (*Pool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("python worker pool size must be positive: %v", size)
+	}
+
+	p := &Pool{
+		runner:  r,
+		size:    size,
+		idle:    make(chan *worker, size),
+		closing: make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		w, err := startWorker(p.runner)
+		if err != nil {
+			close(p.closing)
+			for len(p.idle) > 0 {
+				(<-p.idle).stop()
+			}
+			return nil, err
+		}
+		p.idle <- w
+	}
+	return p, nil
+}
+
+// Size return the number of workers of the pool
+func (p *Pool) Size() int {
+	return p.size
+}
+
+func (p *Pool) Call(scriptPath string, funcName string, params ...interface{}) PResult {
+	return p.Invoke(scriptPath, funcName, params)
+}
+
+func (p *Pool) Invoke(scriptPath string, funcName string, params []interface{}) PResult {
+	return p.InvokeContext(context.Background(), scriptPath, funcName, params)
+}
+
+func (p *Pool) CallContext(ctx context.Context, scriptPath string, funcName string, params ...interface{}) PResult {
+	return p.InvokeContext(ctx, scriptPath, funcName, params)
+}
+
+// InvokeContext is like Invoke, but the worker is killed when the context is done,
+// it will be restarted by next invocation
+func (p *Pool) InvokeContext(ctx context.Context, scriptPath string, funcName string, params []interface{}) PResult {
+	return p.doInvoke(ctx, invocation{scriptPath: scriptPath, funcName: funcName, params: params})
+}
+
+// Close stop accepting invocations, wait running invocations to finish and stop all workers
+func (p *Pool) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.closing)
+		for i := 0; i < cap(p.idle); i++ {
+			w := <-p.idle
+			if e := w.stop(); e != nil && err == nil {
+				err = e
+			}
+		}
+	})
+	return err
+}
+
+func (p *Pool) doInvoke(ctx context.Context, inv invocation) PResult {
+	result, _ := p.invokeFunc(ctx, inv, false)
+	return result
+}
+
+// invokeFunc invoke function in an idle worker, keep tells the worker to keep the result as an object.
+// It also return the worker and its response.
+func (p *Pool) invokeFunc(ctx context.Context, inv invocation, keep bool) (PResult, *workerReply) {
+	args := inv.payload()
+	request := poolRequest{Args: args.Args, Kwargs: args.Kwargs, Keep: keep}
+	request.InitArgs, request.InitKwargs = args.InitArgs, args.InitKwargs
+	return p.send(ctx, inv, request)
+}
+
+// invokeBatch invoke function with every params of paramsList in one request to an idle worker, which imports the
+// function once, see Runner.InvokeBatch
+func (p *Pool) invokeBatch(ctx context.Context, inv invocation, paramsList [][]interface{}) []PResult {
+	results := make([]PResult, len(paramsList))
+	if len(paramsList) < 1 {
+		return results
+	}
+
+	calls := make([]payload, len(paramsList))
+	for i, params := range paramsList {
+		call := inv
+		call.params = params
+		calls[i] = call.payload()
+	}
+	result, _ := p.send(ctx, inv, poolRequest{Op: "batch", Calls: calls})
+	fail := func(err error) []PResult {
+		for i := range results {
+			results[i] = result
+			results[i].Exception = err
+		}
+		return results
+	}
+	if !result.NoError {
+		return fail(result.Exception)
+	}
+
+	var batch []batchResult
+	if err := json.Unmarshal([]byte(result.JsonRepresentation), &batch); err != nil || len(batch) != len(paramsList) {
+		return fail(fmt.Errorf("invoke python function error: unexpected batch result: %v: %v", err, result.JsonRepresentation))
+	}
+	for i, b := range batch {
+		results[i] = result
+		results[i].NoError = b.Ok
+		results[i].JsonRepresentation = b.Result
+		results[i].Output = b.Output
+		if b.Ok {
+			results[i].Exception = exceptionError("")
+		} else {
+			results[i].Exception = b.Exception
+		}
+	}
+	return results
+}
+
+// send complete request with the function of invocation and send it to an idle worker
+func (p *Pool) send(ctx context.Context, inv invocation, request poolRequest) (PResult, *workerReply) {
+	result := PResult{}
+	if err := inv.check(); err != nil {
+		result.Exception = err
+		return result, nil
+	}
+
+	request.Func, request.Method = inv.funcName, inv.method
+	request.Module, request.Path = inv.importFrom(p.runner.importPaths())
+	if len(request.Path) > 0 {
+		request.Script, _ = filepath.Abs(inv.scriptPath)
+	}
+	result.PythonPath = strings.Join(append(p.runner.PythonPaths(), request.Path), string(os.PathListSeparator))
+	result.PythonPath = strings.Trim(result.PythonPath, string(os.PathListSeparator))
+	result.Process = p.runner.processConfig(p.runner.Environ())
+
+	w, err := p.acquire(ctx)
+	if err == ErrTimeout || err == ErrCanceled {
+		result.Exception = err
+		return result, nil
+	}
+	if err != nil {
+		result.Exception = fmt.Errorf("invoke python function error: %v", err)
+		return result, nil
+	}
+	reply := w.invoke(ctx, request, &result)
+	p.release(w)
+	return result, reply
+}
+
+// acquire take an idle worker, restart it if it has exited
+func (p *Pool) acquire(ctx context.Context) (*worker, error) {
+	select {
+	case <-p.closing:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, contextError(ctx.Err())
+	default:
+	}
+
+	select {
+	case w := <-p.idle:
+		if !w.exited() {
+			return w, nil
+		}
+		nw, err := startWorker(p.runner)
+		if err != nil {
+			p.idle <- w
+			return nil, fmt.Errorf("restart python worker error: %v", err)
+		}
+		return nw, nil
+	case <-p.closing:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, contextError(ctx.Err())
+	}
+}
+
+func (p *Pool) release(w *worker) {
+	p.idle <- w
+}
+
+func startWorker(r *Runner) (*worker, error) {
+	cmd := exec.Command(r.executable, "-u", "-c", PythonWorkerScript)
+	setProcessGroup(cmd)
+	r.setupCommand(cmd, "")
+
+	w := &worker{
+		cmd:       cmd,
+		output:    &tailBuffer{limit: workerOutputLimit},
+		callbacks: r.callbacks,
+		threshold: r.payloadThreshold,
+		done:      make(chan struct{}),
+	}
+	cmd.Stderr = w.output
+
+	// files to close in current process after worker started
+	var childFiles []*os.File
+	if resultChannelSupported {
+		requestReader, requestWriter, err := os.Pipe()
+		if err != nil {
+			return nil, fmt.Errorf("open request channel of python worker error: %v", err)
+		}
+		responseReader, responseWriter, err := os.Pipe()
+		if err != nil {
+			requestReader.Close()
+			requestWriter.Close()
+			return nil, fmt.Errorf("open response channel of python worker error: %v", err)
+		}
+		cmd.ExtraFiles = []*os.File{requestReader, responseWriter}
+		cmd.Args = append(cmd.Args, strconv.Itoa(extraFilesFd), strconv.Itoa(extraFilesFd+1))
+		cmd.Stdout = w.output
+		childFiles = cmd.ExtraFiles
+		w.requests = requestWriter
+		w.responses = bufio.NewReader(responseReader)
+		w.closers = []io.Closer{requestWriter, responseReader}
+	} else {
+		sin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("pipe stdin of python worker error: %v", err)
+		}
+		sout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("pipe stdout of python worker error: %v", err)
+		}
+		cmd.Args = append(cmd.Args, "-1", "-1")
+		w.requests = sin
+		w.responses = bufio.NewReader(sout)
+	}
+
+	err := cmd.Start()
+	for _, f := range childFiles {
+		f.Close()
+	}
+	if err != nil {
+		w.close()
+		return nil, fmt.Errorf("start python worker error: %v", err)
+	}
+	go func() {
+		cmd.Wait()
+		close(w.done)
+	}()
+	return w, nil
+}
+
+// workerReply is the response of a worker and the worker itself
+type workerReply struct {
+	worker   *worker
+	response poolResponse
+}
+
+// invoke send request to worker and fill result with its response, the reply is nil if round trip failed
+func (w *worker) invoke(ctx context.Context, request poolRequest, result *PResult) *workerReply {
+	if request.Args == nil {
+		request.Args = []interface{}{}
+	}
+	if w.threshold > 0 {
+		request.Threshold = w.threshold
+	}
+	bs, classes, err := marshalClasses(request)
+	if err == nil && len(classes) > 0 {
+		request.Classes = classes
+		bs, err = Marshal(request)
+	}
+	if err != nil {
+		result.Exception = fmt.Errorf("invoke python function error: can not serialize request to json value: %v", err)
+		return nil
+	}
+
+	line, remove, err := w.requestLine(bs)
+	if err != nil {
+		result.Exception = fmt.Errorf("invoke python function error: %v", err)
+		return nil
+	}
+	defer remove()
+	result.TempScript = string(line)
+	if len(line) < len(bs) {
+		result.TempScript += fmt.Sprintf("  # %v bytes of json request in a temp file", len(bs))
+	}
+
+	w.lock.Lock()
+	output := bytes.Buffer{}
+	response, err := w.roundTrip(ctx, line, &output)
+	w.lock.Unlock()
+	result.Output = output.String()
+	if err == ErrTimeout || err == ErrCanceled {
+		result.Exception = err
+		return nil
+	}
+	if err != nil {
+		result.Exception = fmt.Errorf("invoke python function error: %v", err)
+		return nil
+	}
+
+	result.JsonRepresentation = string(response.Result)
+	if response.Ok {
+		result.NoError = true
+		result.Exception = errors.New("")
+	} else {
+		result.Exception = response.Exception
+	}
+	return &workerReply{worker: w, response: response}
+}
+
+// requestLine return the line sent to worker for json request bs, a request larger than the payload threshold is
+// written to a temp file read by the worker, which is removed by the returned function after the round trip
+func (w *worker) requestLine(bs []byte) ([]byte, func(), error) {
+	if w.threshold < 1 || len(bs) <= w.threshold {
+		return bs, func() {}, nil
+	}
+	f, err := ioutil.TempFile("", "pfunc_payload_")
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not write request to temp file: %v", err)
+	}
+	remove := func() { os.Remove(f.Name()) }
+	_, err = f.Write(bs)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		remove()
+		return nil, nil, fmt.Errorf("can not write request to temp file: %v", err)
+	}
+	line, _ := json.Marshal(map[string]string{"payload": f.Name()})
+	return line, remove, nil
+}
+
+// roundTrip send one request to worker and wait its response, kill the worker when the context is done first.
+// Output of the request is written to output as the worker sends it.
+func (w *worker) roundTrip(ctx context.Context, request []byte, output *bytes.Buffer) (poolResponse, error) {
+	type reply struct {
+		response poolResponse
+		err      error
+	}
+	replies := make(chan reply, 1)
+	go func() {
+		response, err := w.exchange(ctx, request, output)
+		replies <- reply{response, err}
+	}()
+
+	select {
+	case r := <-replies:
+		return r.response, r.err
+	case <-ctx.Done():
+		killProcessGroup(w.cmd)
+		<-w.done
+		<-replies
+		return poolResponse{}, contextError(ctx.Err())
+	}
+}
+
+// exchange write one request line to worker and read its response line, output written by worker before the
+// response is written to output, and callback requests are answered in the request channel. A large response is
+// written by worker to a temp file named by the response line.
+func (w *worker) exchange(ctx context.Context, request []byte, output *bytes.Buffer) (poolResponse, error) {
+	if _, err := w.requests.Write(append(request, '\n')); err != nil {
+		return poolResponse{}, w.crashed(err)
+	}
+	for {
+		line, err := w.responses.ReadBytes('\n')
+		if err != nil {
+			return poolResponse{}, w.crashed(err)
+		}
+		message := struct {
+			poolResponse
+			callbackRequest
+			Write   *string `json:"write"`
+			Payload string  `json:"payload"`
+		}{}
+		if err := json.Unmarshal(line, &message); err != nil {
+			return poolResponse{}, fmt.Errorf("unexpected python worker response: %v: %v", err, string(line))
+		}
+		if message.Write != nil {
+			output.WriteString(*message.Write)
+			continue
+		}
+		if len(message.Payload) > 0 {
+			return readResponseFile(message.Payload)
+		}
+		if len(message.Callback) < 1 {
+			return message.poolResponse, nil
+		}
+		if _, err := w.requests.Write(w.callbacks.reply(ctx, message.callbackRequest)); err != nil {
+			return poolResponse{}, w.crashed(err)
+		}
+	}
+}
+
+// readResponseFile decode the response written to file by worker and remove the file
+func readResponseFile(path string) (poolResponse, error) {
+	defer os.Remove(path)
+	response := poolResponse{}
+	f, err := os.Open(path)
+	if err != nil {
+		return response, fmt.Errorf("read python worker response file error: %v", err)
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&response); err != nil {
+		return response, fmt.Errorf("unexpected python worker response: %v: %v", err, path)
+	}
+	return response, nil
+}
+
+// crashed kill the worker after a broken round trip and describe why it failed
+func (w *worker) crashed(err error) error {
+	killProcessGroup(w.cmd)
+	<-w.done
+	w.close()
+	return fmt.Errorf("python worker exited: %v: %v\n%v", w.cmd.ProcessState, err, w.output.String())
+}
+
+func (w *worker) exited() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop close request channel to end the request loop of worker, kill it if it does not exit in time
+func (w *worker) stop() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	defer w.close()
+	w.requests.Close()
+	select {
+	case <-w.done:
+		return nil
+	case <-time.After(workerStopTimeout):
+		killProcessGroup(w.cmd)
+		<-w.done
+		return fmt.Errorf("python worker did not exit in %v, killed", workerStopTimeout)
+	}
+}
+
+// close release channels of worker in current process
+func (w *worker) close() {
+	for _, c := range w.closers {
+		c.Close()
+	}
+}
+
+// tailBuffer is a goroutine safe writer which only keeps the last limit bytes
+type tailBuffer struct {
+	lock  sync.Mutex
+	limit int
+	data  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = b.data[len(b.data)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return string(b.data)
+}
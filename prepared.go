@@ -0,0 +1,66 @@
+package pfunc
+
+import (
+	"context"
+)
+
+// Prepared is an immutable wrapped python function made by WrapInfo.Prepare, it takes params per call,
+// so one Prepared can be built once and called from many goroutines:
+//
+//	divide := pfunc.Func("math.py", "divide").Return(0).ParamDefaults(0, 1).Prepare()
+//	result, err := divide.Call(6, 3)
+//
+// Changes of the WrapInfo after Prepare do not affect the Prepared.
+type Prepared struct {
+	w *WrapInfo
+}
+
+// Prepare make an immutable copy of the wrapped function, params set by Params are ignored,
+// params are given to Prepared.Call instead
+func (w *WrapInfo) Prepare() *Prepared {
+	c := *w
+	c.paramTypes = nil
+	c.paramValues = nil
+	c.paramDefaultValues = append([]interface{}(nil), w.paramDefaultValues...)
+	c.wrapError = append([]error(nil), w.wrapError...)
+	c.Keywords = copyKeywords(w.Keywords)
+	c.initParams = append([]interface{}(nil), w.initParams...)
+	c.initKw = copyKeywords(w.initKw)
+	return &Prepared{w: &c}
+}
+
+// Call invoke the function with params, default values are used for missing params
+func (p *Prepared) Call(params ...interface{}) (interface{}, error) {
+	return p.CallContext(context.Background(), params...)
+}
+
+// CallContext is like Call, but the python process is killed when the context is done,
+// then ErrTimeout or ErrCanceled is returned
+func (p *Prepared) CallContext(ctx context.Context, params ...interface{}) (interface{}, error) {
+	if err := p.w.ready(); err != nil {
+		return nil, err
+	}
+	return p.w.call(ctx, params)
+}
+
+// Batch invoke the function for every params in one python process, see WrapInfo.DoBatch
+func (p *Prepared) Batch(ctx context.Context, argsList [][]interface{}) ([]interface{}, []error) {
+	return p.w.DoBatchContext(ctx, argsList)
+}
+
+// Map invoke the function for every element of inputs in parallel, see Map
+func (p *Prepared) Map(ctx context.Context, inputs interface{}, opts ...MapOption) ([]interface{}, error) {
+	return Map(ctx, p.w, inputs, opts...)
+}
+
+// copyKeywords return a copy of keyword params, or nil when there is none
+func copyKeywords(kw map[string]interface{}) map[string]interface{} {
+	if kw == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(kw))
+	for k, v := range kw {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,28 @@
+//go:build windows
+// +build windows
+
+package pfunc
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup is not needed on windows, taskkill finds children by process tree
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// killProcessGroup kill the started command and all its child processes
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	cmd.Process.Kill()
+}
+
+// cpuTimeExceeded is always false, there is no cpu time limit on windows
+func cpuTimeExceeded(state *os.ProcessState) bool {
+	return false
+}
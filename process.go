@@ -0,0 +1,171 @@
+package pfunc
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// environment variable telling temp script and pool workers the entries to add to sys.path
+const sysPathEnv = "PFUNC_SYS_PATH"
+
+// pythonProcessRuntime is the part of PythonRuntime adding the entries of sysPathEnv to sys.path
+const pythonProcessRuntime string = `
+
+def pfunc_extend_sys_path():
+    paths = os.environ.pop("PFUNC_SYS_PATH", "")
+    if not paths:
+        return
+    paths = json.loads(paths)
+    sys.path[0:0] = paths.get("prepend") or []
+    sys.path.extend(paths.get("append") or [])
+`
+
+// EnvMode tells how variables set by WithEnvMap make the environment of python processes
+type EnvMode int
+
+const (
+	// EnvInherit set the variables on top of the inherited environment
+	EnvInherit EnvMode = iota
+	// EnvReplace use only the variables as environment
+	EnvReplace
+	// EnvClear remove the variables from the inherited environment, their values are ignored
+	EnvClear
+)
+
+// ProcessConfig describe how the python process of an invocation was started,
+// for pool invocations it is the configuration the worker was started with
+type ProcessConfig struct {
+	Executable     string
+	WorkDir        string
+	Env            []string
+	SysPathPrepend []string
+	SysPathAppend  []string
+	Isolated       bool
+}
+
+// WithEnvMap set, replace or clear environment variables of python processes, the inherited environment is the one
+// set by WithEnv or the environment of current process
+func WithEnvMap(mode EnvMode, vars map[string]string) Option {
+	return func(r *Runner) {
+		r.envMode = mode
+		r.envVars = map[string]string{}
+		for k, v := range vars {
+			r.envVars[k] = v
+		}
+	}
+}
+
+// WithSysPathPrepend insert entries before sys.path of python processes, so their modules shadow installed ones
+func WithSysPathPrepend(paths ...string) Option {
+	return func(r *Runner) {
+		r.sysPathPrepend = append(append([]string(nil), paths...), r.sysPathPrepend...)
+	}
+}
+
+// WithSysPathAppend append entries after sys.path of python processes, after site-packages
+func WithSysPathAppend(paths ...string) Option {
+	return func(r *Runner) {
+		r.sysPathAppend = append(append([]string(nil), r.sysPathAppend...), paths...)
+	}
+}
+
+// WithIsolated drop inherited PYTHON* variables like PYTHONPATH and PYTHONHOME from environment of python
+// processes, variables set by WithEnvMap and entries of WithPythonPaths are kept
+func WithIsolated(isolated bool) Option {
+	return func(r *Runner) {
+		r.isolated = isolated
+	}
+}
+
+// With make the wrapped function invoked by a copy of its runner with options applied, like a different
+// working directory or environment. Pool workers use the configuration of the runner which started the pool.
+func (w *WrapInfo) With(options ...Option) *WrapInfo {
+	runner := w.runner
+	if runner == nil {
+		runner = DefaultRunner()
+	}
+	w.runner = runner.With(options...)
+	return w
+}
+
+// baseEnviron return the inherited environment with variables of WithEnvMap applied
+func (r *Runner) baseEnviron() []string {
+	var env []string
+	if r.env != nil {
+		env = append(env, r.env...)
+	} else {
+		env = os.Environ()
+	}
+	if r.isolated {
+		env = removeEnv(env, func(key string) bool { return strings.HasPrefix(key, "PYTHON") })
+	}
+
+	switch r.envMode {
+	case EnvReplace:
+		env = []string{}
+		fallthrough
+	case EnvInherit:
+		for k, v := range r.envVars {
+			env = removeEnv(env, func(key string) bool { return key == k })
+			env = append(env, k+"="+v)
+		}
+	case EnvClear:
+		env = removeEnv(env, func(key string) bool {
+			_, ok := r.envVars[key]
+			return ok
+		})
+	}
+	return env
+}
+
+// removeEnv return items of env whose keys are not matched
+func removeEnv(env []string, match func(key string) bool) []string {
+	kept := []string{}
+	for _, item := range env {
+		if !match(strings.SplitN(item, "=", 2)[0]) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// importPaths return directories to find the import path of script in, in the order of sys.path
+func (r *Runner) importPaths() []string {
+	var paths []string
+	paths = append(paths, r.sysPathPrepend...)
+	paths = append(paths, r.PythonPaths()...)
+	return append(paths, r.sysPathAppend...)
+}
+
+// setupCommand set working directory and environment of runner to python command,
+// appendPythonPath is appended to PYTHONPATH when it is not empty
+func (r *Runner) setupCommand(cmd *exec.Cmd, appendPythonPath string) {
+	cmd.Dir = r.workDir
+	cmd.Env = r.Environ()
+	if len(appendPythonPath) > 0 {
+		AddEnv(&cmd.Env, PythonPath, appendPythonPath)
+	}
+	if len(r.sysPathPrepend) > 0 || len(r.sysPathAppend) > 0 {
+		bs, _ := json.Marshal(map[string][]string{"prepend": r.sysPathPrepend, "append": r.sysPathAppend})
+		cmd.Env = append(cmd.Env, sysPathEnv+"="+string(bs))
+	}
+	r.setupEncoding(cmd)
+}
+
+// processConfig describe python process started with env
+func (r *Runner) processConfig(env []string) ProcessConfig {
+	workDir := r.workDir
+	if len(workDir) < 1 {
+		workDir, _ = os.Getwd()
+	}
+	return ProcessConfig{
+		Executable:     r.executable,
+		WorkDir:        workDir,
+		Env:            removeEnv(env, func(key string) bool { return key == sysPathEnv || key == encodingEnv }),
+		SysPathPrepend: append([]string(nil), r.sysPathPrepend...),
+		SysPathAppend:  append([]string(nil), r.sysPathAppend...),
+		Isolated:       r.isolated,
+	}
+}
@@ -0,0 +1,379 @@
+package pfunc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// environment variable telling temp script the limits and sandbox to apply before importing function
+const restrictEnv = "PFUNC_RESTRICT"
+
+// pythonRestrictRuntime is the part of PythonRuntime applying limits and sandbox in python
+const pythonRestrictRuntime string = `
+
+def pfunc_restrict():
+    config = os.environ.pop("PFUNC_RESTRICT", "")
+    if not config:
+        return
+    config = json.loads(config)
+    # import before the sandbox changes user, who may not read python modules
+    if config.get("rlimits"):
+        import resource
+    if config.get("sandbox") is not None:
+        pfunc_sandbox(config["sandbox"])
+    if config.get("rlimits"):
+        for name, value in config["rlimits"].items():
+            resource.setrlimit(getattr(resource, name), tuple(value))
+    # python started with -E -S, so PYTHONPATH and site, which may run sitecustomize, come after the restriction
+    if config.get("site"):
+        sys.path[1:1] = [p for p in os.environ.get("PYTHONPATH", "").split(os.pathsep) if p]
+        import site
+        if sys.version_info[0] >= 3:
+            site.main()
+
+
+def pfunc_sandbox(sandbox):
+    import ctypes
+    import ctypes.util
+    import re
+    libc = ctypes.CDLL(ctypes.util.find_library("c"), use_errno=True)
+    ms_rdonly, ms_remount, ms_bind, ms_rec, ms_private = 1, 32, 4096, 1 << 14, 1 << 18
+
+    def mount(target, flags, source=None):
+        if libc.mount(source and source.encode(), target.encode(), None, ctypes.c_ulong(flags), None) != 0:
+            e = ctypes.get_errno()
+            raise OSError(e, "sandbox mount " + target + " error: " + os.strerror(e))
+
+    # mounts of the new mount namespace must not propagate to the host
+    mount("/", ms_rec | ms_private)
+    if sandbox.get("readonly"):
+        writable = [os.path.realpath(p) for p in sandbox.get("writable") or []]
+        for p in writable:
+            mount(p, ms_bind | ms_rec, p)
+        points = []
+        mountinfo = open("/proc/self/mountinfo")
+        for line in mountinfo:
+            point = re.sub(r"\\([0-7]{3})", lambda m: chr(int(m.group(1), 8)), line.split()[4])
+            if not [w for w in writable if point == w or point.startswith(w.rstrip("/") + "/")]:
+                points.append(point)
+        mountinfo.close()
+        for point in points:
+            try:
+                flags = os.statvfs(point).f_flag
+            except OSError:
+                continue
+            # keep nosuid, nodev, noexec and atime flags, which may be locked
+            locked = flags & (2 | 4 | 8 | 1024 | 2048)
+            if flags & 4096:
+                locked |= 1 << 21
+            mount(point, ms_rdonly | ms_remount | ms_bind | locked)
+
+    # no new privileges by setuid programs, then drop root or all capabilities
+    libc.prctl(38, 1, 0, 0, 0)
+    if sandbox.get("gid") is not None:
+        os.setgroups([])
+        os.setgid(sandbox["gid"])
+    if sandbox.get("uid") is not None:
+        os.setuid(sandbox["uid"])
+        return
+    for cap in range(64):
+        libc.prctl(24, cap, 0, 0, 0)
+
+    class Header(ctypes.Structure):
+        _fields_ = [("version", ctypes.c_uint32), ("pid", ctypes.c_int)]
+
+    class Data(ctypes.Structure):
+        _fields_ = [("effective", ctypes.c_uint32), ("permitted", ctypes.c_uint32), ("inheritable", ctypes.c_uint32)]
+
+    if libc.capset(ctypes.byref(Header(0x20080522, 0)), (Data * 2)()) != 0:
+        e = ctypes.get_errno()
+        raise OSError(e, "sandbox drop capabilities error: " + os.strerror(e))
+`
+
+// names of limits in LimitError
+const (
+	LimitCPU        = "cpu"
+	LimitMemory     = "memory"
+	LimitOpenFiles  = "files"
+	LimitProcesses  = "processes"
+	LimitOutput     = "output"
+	LimitFilesystem = "filesystem"
+	LimitNetwork    = "network"
+)
+
+// Limits restrict resources of a python process, zero values mean no limit. On linux they are set by prlimit on
+// the started process before it reads any python code of pfunc, so python itself is counted too, elsewhere python
+// sets them before the function is imported. A restricted python starts with -E -S, it adds
+// PYTHONPATH to sys.path and imports site once limits and sandbox are applied, other PYTHON* variables are ignored.
+type Limits struct {
+	// CPUTime is the cpu time of process, rounded up to seconds
+	CPUTime time.Duration
+	// AddressSpace is the max bytes of virtual memory, python raises MemoryError beyond it
+	AddressSpace uint64
+	// OpenFiles is the max number of open file descriptors
+	OpenFiles uint64
+	// OutputSize is the max bytes of stdout and stderr, the process is killed when it writes more
+	OutputSize int
+	// NoChildProcesses forbids creating processes and threads, it is not enforced for root, see Sandbox.UID
+	NoChildProcesses bool
+}
+
+// Sandbox isolates a python process in new linux namespaces. The process has no network unless Network is set,
+// and the filesystem is read only except Writable directories when ReadOnly is set. It runs as UID and GID when
+// they are set, which requires root, otherwise all its capabilities are dropped.
+type Sandbox struct {
+	Network  bool
+	ReadOnly bool
+	Writable []string
+	UID      *int
+	GID      *int
+}
+
+// LimitError is the exception of an invocation which exceeded a limit or was denied by the sandbox,
+// Err is the python exception or the reason of the killed process
+type LimitError struct {
+	Limit string
+	Err   error
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("python function exceeded %v limit: %v", e.Limit, e.Err)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// WithLimits restrict resources of python processes. A runner with limits or a sandbox always starts a new python
+// process for every invocation, pools are not used.
+func WithLimits(l Limits) Option {
+	return func(r *Runner) {
+		r.limits = l
+	}
+}
+
+// WithSandbox run python processes in a linux sandbox, nil means no sandbox, see WithLimits
+func WithSandbox(s *Sandbox) Option {
+	return func(r *Runner) {
+		r.sandbox = s
+	}
+}
+
+// restricted tells if python processes of runner have limits or a sandbox
+func (r *Runner) restricted() bool {
+	return r.limits != Limits{} || r.sandbox != nil
+}
+
+// restrictConfig is the json of restrictEnv, Site tells python to add PYTHONPATH and run site once restricted
+type restrictConfig struct {
+	Rlimits map[string][2]uint64 `json:"rlimits,omitempty"`
+	Sandbox *sandboxConfig       `json:"sandbox,omitempty"`
+	Site    bool                 `json:"site"`
+}
+
+// sandboxConfig is the part of sandbox applied by python
+type sandboxConfig struct {
+	ReadOnly bool     `json:"readonly"`
+	Writable []string `json:"writable"`
+	UID      *int     `json:"uid,omitempty"`
+	GID      *int     `json:"gid,omitempty"`
+}
+
+// restrict tell python of command the limits and sandbox to apply, and start it in new namespaces of sandbox
+func (r *Runner) restrict(cmd *exec.Cmd) error {
+	if !r.restricted() {
+		return nil
+	}
+	// python ignores PYTHON* variables and does not import site at startup, so no code like sitecustomize on
+	// PYTHONPATH runs before limits and sandbox are applied
+	cmd.Args = append([]string{cmd.Args[0], "-E", "-S"}, cmd.Args[1:]...)
+	config := restrictConfig{Rlimits: r.rlimits(), Site: true}
+	if s := r.sandbox; s != nil {
+		if err := sandboxProcess(cmd, s); err != nil {
+			return err
+		}
+		config.Sandbox = &sandboxConfig{ReadOnly: s.ReadOnly, Writable: append([]string{}, s.Writable...), UID: s.UID, GID: s.GID}
+	}
+
+	bs, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	cmd.Env = append(cmd.Env, restrictEnv+"="+string(bs))
+	return nil
+}
+
+// rlimits return soft and hard rlimits of limits of runner by their names in python resource module
+func (r *Runner) rlimits() map[string][2]uint64 {
+	rlimits := map[string][2]uint64{}
+	if l := r.limits; l.CPUTime > 0 {
+		seconds := uint64((l.CPUTime + time.Second - 1) / time.Second)
+		// python is killed by SIGXCPU at the soft limit, the hard one is a fallback
+		rlimits["RLIMIT_CPU"] = [2]uint64{seconds, seconds + 1}
+	}
+	if l := r.limits; l.AddressSpace > 0 {
+		rlimits["RLIMIT_AS"] = [2]uint64{l.AddressSpace, l.AddressSpace}
+	}
+	if l := r.limits; l.OpenFiles > 0 {
+		rlimits["RLIMIT_NOFILE"] = [2]uint64{l.OpenFiles, l.OpenFiles}
+	}
+	if r.limits.NoChildProcesses {
+		rlimits["RLIMIT_NPROC"] = [2]uint64{0, 0}
+	}
+	return rlimits
+}
+
+// start start python of command and set rlimits of runner on it before it reads the temp script from stdin, so
+// no python code of pfunc or of the function runs before limits are set. Python sets them again before importing
+// the function, which is the only place they are set where the process can not be limited from go.
+func (r *Runner) start(cmd *exec.Cmd) error {
+	rlimits := r.rlimits()
+	if len(rlimits) < 1 {
+		return cmd.Start()
+	}
+	stdin := &gatedReader{reader: cmd.Stdin, open: make(chan struct{})}
+	cmd.Stdin = stdin
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	err := limitProcess(cmd.Process.Pid, rlimits)
+	if err != nil {
+		killProcessGroup(cmd)
+	}
+	close(stdin.open)
+	if err != nil {
+		cmd.Wait()
+		return fmt.Errorf("set limits error: %v", err)
+	}
+	return nil
+}
+
+// gatedReader is stdin of python which can not be read before it is opened
+type gatedReader struct {
+	reader io.Reader
+	open   chan struct{}
+}
+
+func (g *gatedReader) Read(p []byte) (int, error) {
+	<-g.open
+	return g.reader.Read(p)
+}
+
+// exceeded return the LimitError of a python process killed for exceeding cpu time or output size, or nil
+func (r *Runner) exceeded(cmd *exec.Cmd, output *limitedOutput) error {
+	if output.exceeded() {
+		return &LimitError{Limit: LimitOutput, Err: fmt.Errorf("output exceeded %v bytes", r.limits.OutputSize)}
+	}
+	if r.limits.CPUTime > 0 && cpuTimeExceeded(cmd.ProcessState) {
+		return &LimitError{Limit: LimitCPU, Err: fmt.Errorf("python exceeded cpu time %v: %v", r.limits.CPUTime, cmd.ProcessState)}
+	}
+	return nil
+}
+
+// limitError wrap a python exception caused by limits or sandbox of runner in LimitError
+func (r *Runner) limitError(err error) error {
+	var pe *PythonError
+	if !r.restricted() || !errors.As(err, &pe) {
+		return err
+	}
+	limit := ""
+	errno, _ := pythonErrno(pe)
+	switch {
+	case pe.Type == "MemoryError" && r.limits.AddressSpace > 0:
+		limit = LimitMemory
+	case errno == syscall.EMFILE && r.limits.OpenFiles > 0:
+		limit = LimitOpenFiles
+	case errno == syscall.EAGAIN && r.limits.NoChildProcesses:
+		limit = LimitProcesses
+	case errno == syscall.EROFS && r.sandbox != nil && r.sandbox.ReadOnly:
+		limit = LimitFilesystem
+	case networkDenied(pe) && r.sandbox != nil && !r.sandbox.Network:
+		limit = LimitNetwork
+	default:
+		return err
+	}
+	return &LimitError{Limit: limit, Err: err}
+}
+
+// networkDenied tells if a python exception, or the one it was raised from or while handling, like URLError of
+// urllib, is an unreachable network or a failed host name lookup. socket.gaierror has EAI_* codes instead of errno.
+func networkDenied(pe *PythonError) bool {
+	if pe == nil {
+		return false
+	}
+	if errno, _ := pythonErrno(pe); errno == syscall.ENETUNREACH || errno == syscall.ENETDOWN {
+		return true
+	}
+	if pe.Type == "gaierror" && (pe.Module == "socket" || pe.Module == "_socket") {
+		return true
+	}
+	return networkDenied(pe.Cause) || networkDenied(pe.Context)
+}
+
+// pythonErrno return errno of a python OSError, which is the first of its two or more args
+func pythonErrno(pe *PythonError) (syscall.Errno, bool) {
+	if len(pe.Args) < 2 {
+		return 0, false
+	}
+	n, ok := pe.Args[0].(float64)
+	if !ok {
+		return 0, false
+	}
+	return syscall.Errno(n), true
+}
+
+// limitedOutput collect stdout and stderr of python, and kill it when they exceed size bytes, 0 means no limit
+type limitedOutput struct {
+	lock   sync.Mutex
+	size   int
+	total  int
+	cmd    *exec.Cmd
+	killed bool
+}
+
+// outputWriter is stdout or stderr of a limitedOutput
+type outputWriter struct {
+	output *limitedOutput
+	buffer *bytes.Buffer
+}
+
+func newLimitedOutput(cmd *exec.Cmd, size int) *limitedOutput {
+	return &limitedOutput{cmd: cmd, size: size}
+}
+
+// writer return a writer of output to buffer
+func (o *limitedOutput) writer(buffer *bytes.Buffer) *outputWriter {
+	return &outputWriter{output: o, buffer: buffer}
+}
+
+// exceeded tells if python was killed for too much output
+func (o *limitedOutput) exceeded() bool {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return o.killed
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	o := w.output
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if o.killed {
+		return len(p), nil
+	}
+	if o.size > 0 && o.total+len(p) > o.size {
+		w.buffer.Write(p[:o.size-o.total])
+		o.total = o.size
+		o.killed = true
+		killProcessGroup(o.cmd)
+		return len(p), nil
+	}
+	o.total += len(p)
+	return w.buffer.Write(p)
+}
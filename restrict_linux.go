@@ -0,0 +1,62 @@
+package pfunc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// resources of prlimit by their names in python resource module. RLIMIT_NPROC is not in package syscall and
+// differs between architectures, python sets it before importing the function, and python does not start
+// processes or threads before.
+var rlimitResources = map[string]int{
+	"RLIMIT_CPU":    syscall.RLIMIT_CPU,
+	"RLIMIT_AS":     syscall.RLIMIT_AS,
+	"RLIMIT_NOFILE": syscall.RLIMIT_NOFILE,
+}
+
+// limitProcess set rlimits on process pid by prlimit, a process which already exited is not an error
+func limitProcess(pid int, rlimits map[string][2]uint64) error {
+	for name, value := range rlimits {
+		resource, ok := rlimitResources[name]
+		if !ok {
+			continue
+		}
+		limit := syscall.Rlimit{Cur: value[0], Max: value[1]}
+		_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource),
+			uintptr(unsafe.Pointer(&limit)), 0, 0, 0)
+		if errno == syscall.ESRCH {
+			return nil
+		}
+		if errno != 0 {
+			return fmt.Errorf("%v: %v", name, errno)
+		}
+	}
+	return nil
+}
+
+// sandboxProcess start command in new mount and network namespaces, and in a new user namespace mapping
+// the current user to root when it is not root. Python makes the filesystem read only and drops privileges.
+func sandboxProcess(cmd *exec.Cmd, s *Sandbox) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	attr := cmd.SysProcAttr
+	attr.Cloneflags = syscall.CLONE_NEWNS
+	if !s.Network {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+	if os.Getuid() == 0 {
+		return nil
+	}
+	if s.UID != nil || s.GID != nil {
+		return fmt.Errorf("invoke python function error: sandbox uid and gid require root")
+	}
+	attr.Cloneflags |= syscall.CLONE_NEWUSER
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+	attr.GidMappingsEnableSetgroups = false
+	return nil
+}
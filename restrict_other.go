@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package pfunc
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// limitProcess does nothing without prlimit, python sets rlimits before importing the function
+func limitProcess(pid int, rlimits map[string][2]uint64) error {
+	return nil
+}
+
+// sandboxProcess is not supported without linux namespaces
+func sandboxProcess(cmd *exec.Cmd, s *Sandbox) error {
+	return fmt.Errorf("invoke python function error: sandbox is only supported on linux")
+}
@@ -0,0 +1,244 @@
+package pfunc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Runner holds the configuration to invoke python functions: python executable, template element names,
+// environment, working directory and PYTHONPATH entries. A runner is immutable after built by NewRunner,
+// so it is safe to use it from many goroutines, and runners with different configurations do not
+// affect each other.
+type Runner struct {
+	executable          string
+	injectVarNamePrefix string
+	returnValueStart    string
+	returnValueEnd      string
+	exceptionStart      string
+	exceptionEnd        string
+	env                 []string
+	workDir             string
+	pythonPaths         []string
+	pool                *Pool
+	callbacks           *callbacks
+	cache               Cache
+	limits              Limits
+	sandbox             *Sandbox
+	envMode             EnvMode
+	envVars             map[string]string
+	sysPathPrepend      []string
+	sysPathAppend       []string
+	isolated            bool
+	payloadThreshold    int
+	encoding            Encoding
+}
+
+// Option configure a runner built by NewRunner
+type Option func(r *Runner)
+
+// runner used by package level functions and configured by package level setters
+var defaultRunner = NewRunner()
+var defaultRunnerLock sync.RWMutex
+
+// NewRunner build a runner with default configuration and apply options to it
+func NewRunner(options ...Option) *Runner {
+	r := &Runner{
+		executable:       "python",
+		callbacks:        newCallbacks(),
+		payloadThreshold: PayloadThresholdDefault,
+	}
+	WithTemplateElementNamesPrefix("")(r)
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// With return a copy of runner with options applied
+func (r *Runner) With(options ...Option) *Runner {
+	c := *r
+	c.env = append([]string(nil), r.env...)
+	c.pythonPaths = append([]string(nil), r.pythonPaths...)
+	for _, option := range options {
+		option(&c)
+	}
+	return &c
+}
+
+// DefaultRunner return the runner used by package level functions like Invoke and Func
+func DefaultRunner() *Runner {
+	defaultRunnerLock.RLock()
+	defer defaultRunnerLock.RUnlock()
+	return defaultRunner
+}
+
+// SetDefaultRunner replace the runner used by package level functions
+func SetDefaultRunner(r *Runner) {
+	defaultRunnerLock.Lock()
+	defer defaultRunnerLock.Unlock()
+	defaultRunner = r
+}
+
+// updateDefaultRunner replace default runner with a copy of it with options applied
+func updateDefaultRunner(options ...Option) {
+	defaultRunnerLock.Lock()
+	defer defaultRunnerLock.Unlock()
+	defaultRunner = defaultRunner.With(options...)
+}
+
+// WithPythonExecutable set the python executable to run
+func WithPythonExecutable(s string) Option {
+	return func(r *Runner) {
+		r.executable = s
+	}
+}
+
+func WithInjectVarNamePrefix(s string) Option {
+	return func(r *Runner) {
+		r.injectVarNamePrefix = s
+	}
+}
+
+// WithReturnValueMarkers set the markers around return value in python output
+func WithReturnValueMarkers(start string, end string) Option {
+	return func(r *Runner) {
+		r.returnValueStart = start
+		r.returnValueEnd = end
+	}
+}
+
+// WithExceptionMarkers set the markers around exception in python output
+func WithExceptionMarkers(start string, end string) Option {
+	return func(r *Runner) {
+		r.exceptionStart = start
+		r.exceptionEnd = end
+	}
+}
+
+// WithTemplateElementNamesPrefix add prefix to default inject var name prefix and markers
+func WithTemplateElementNamesPrefix(s string) Option {
+	return func(r *Runner) {
+		r.injectVarNamePrefix = s + InjectVarNamePrefixDefault
+		r.returnValueStart = s + ReturnValueStartDefault
+		r.returnValueEnd = s + ReturnValueEndDefault
+		r.exceptionStart = s + ExceptionStartDefault
+		r.exceptionEnd = s + ExceptionEndDefault
+	}
+}
+
+// WithEnv set environment of python processes in "key=value" form, instead of environment of current process
+func WithEnv(env []string) Option {
+	return func(r *Runner) {
+		r.env = append([]string(nil), env...)
+	}
+}
+
+// WithWorkDir set working directory of python processes
+func WithWorkDir(dir string) Option {
+	return func(r *Runner) {
+		r.workDir = dir
+	}
+}
+
+// WithPythonPaths append entries to PYTHONPATH of python processes
+func WithPythonPaths(paths ...string) Option {
+	return func(r *Runner) {
+		r.pythonPaths = append(r.pythonPaths, paths...)
+	}
+}
+
+// WithPool dispatch invocations to workers of the pool, nil means starting a python process for every invocation
+func WithPool(p *Pool) Option {
+	return func(r *Runner) {
+		r.pool = p
+	}
+}
+
+func (r *Runner) PythonExecutable() string {
+	return r.executable
+}
+
+func (r *Runner) Pool() *Pool {
+	return r.pool
+}
+
+// Environ return environment of python processes
+func (r *Runner) Environ() []string {
+	env := r.baseEnviron()
+	for _, p := range r.pythonPaths {
+		AddEnv(&env, PythonPath, p)
+	}
+	return env
+}
+
+// PythonPaths return entries of PYTHONPATH of python processes
+func (r *Runner) PythonPaths() []string {
+	env := r.Environ()
+	value, i := GetEnv(&env, PythonPath)
+	if i < 0 || len(strings.TrimSpace(value)) < 1 {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSpace(value), string(os.PathListSeparator))
+}
+
+func (r *Runner) Func(scriptPath string, funcName string) *WrapInfo {
+	w := Func(scriptPath, funcName)
+	w.runner = r
+	return w
+}
+
+func (r *Runner) Call(scriptPath string, funcName string, params ...interface{}) PResult {
+	return r.Invoke(scriptPath, funcName, params)
+}
+
+func (r *Runner) Invoke(scriptPath string, funcName string, params []interface{}) PResult {
+	return r.InvokeContext(context.Background(), scriptPath, funcName, params)
+}
+
+func (r *Runner) CallContext(ctx context.Context, scriptPath string, funcName string, params ...interface{}) PResult {
+	return r.InvokeContext(ctx, scriptPath, funcName, params)
+}
+
+func (r *Runner) InvokeContext(ctx context.Context, scriptPath string, funcName string, params []interface{}) PResult {
+	return r.dispatch(ctx, nil, invocation{scriptPath: scriptPath, funcName: funcName, params: params})
+}
+
+// dispatch invoke in pool p, or pool of runner when p is nil, or in a new python process when no pool is set
+func (r *Runner) dispatch(ctx context.Context, p *Pool, inv invocation) PResult {
+	return r.dispatchCache(ctx, p, r.cache, inv)
+}
+
+// dispatchCache is like dispatch, but results are cached in c when it is not nil
+func (r *Runner) dispatchCache(ctx context.Context, p *Pool, c Cache, inv invocation) PResult {
+	if p == nil {
+		p = r.pool
+	}
+	if r.restricted() {
+		p = nil
+	}
+	if c != nil {
+		// results are keyed by the configuration of the runner starting python, which is the one of the pool
+		keyed := r
+		if p != nil {
+			keyed = p.runner
+		}
+		return keyed.cached(ctx, c, inv, func() PResult {
+			return r.dispatchCache(ctx, p, nil, inv)
+		})
+	}
+	if p != nil {
+		return p.doInvoke(ctx, inv)
+	}
+	return r.doInvoke(ctx, inv)
+}
+
+// NewPool start a pool of size python workers configured by runner
+func (r *Runner) NewPool(size int) (*Pool, error) {
+	if r.restricted() {
+		return nil, fmt.Errorf("invoke python function error: pool workers can not have limits or a sandbox")
+	}
+	return newPool(r.With(WithPool(nil)), size)
+}
@@ -0,0 +1,16 @@
+package pfunc
+
+// PythonRuntime is python code shared by temp scripts and pool workers, it is written to run on both python 2
+// and python 3. It is a part of templates formatted by fmt, so it must not contain percent signs.
+//
+// Every piece is defined next to the go code it works with.
+const PythonRuntime string = pythonChannelRuntime +
+	pythonCallbackRuntime +
+	pythonEncodingRuntime +
+	pythonCodecRuntime +
+	pythonPayloadRuntime +
+	pythonProcessRuntime +
+	pythonRestrictRuntime +
+	pythonInvocationRuntime +
+	pythonEvalRuntime +
+	pythonExceptionRuntime
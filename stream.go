@@ -0,0 +1,298 @@
+package pfunc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"reflect"
+	"strings"
+)
+
+// PythonStreamTemplate iterate result of python function and send every item once it is produced,
+// one message per line, then a message marking the end of items. It runs on both python 2 and python 3.
+const PythonStreamTemplate string = `
+import os
+import sys
+import traceback
+import json
+` + PythonRuntime + `
+pfunc_channel = pfunc_open_channel(%d, "w")
+pfunc_install_runtime(*pfunc_callback_channels())
+pfunc_restrict()
+pfunc_extend_sys_path()
+pfunc_configure_encoding()
+try:
+    from %s import %s
+%s
+    for item in %s:
+        pfunc_send_line(pfunc_channel, "item", pfunc_dumps(item), '%s', '%s')
+    pfunc_send_line(pfunc_channel, "end", "", '%[6]s', '%[7]s')
+except Exception as e:
+    pfunc_send_line(pfunc_channel, "exception", json.dumps(pfunc_exception(e, sys.exc_info()[2])), '%s', '%s')
+pfunc_channel.flush()
+`
+
+// InvokeStream invoke a python generator function, or any function returning an iterable, by the default runner,
+// see Runner.InvokeStream
+func InvokeStream(ctx context.Context, scriptPath string, funcName string, params []interface{}) (<-chan json.RawMessage, <-chan error) {
+	return DefaultRunner().InvokeStream(ctx, scriptPath, funcName, params)
+}
+
+// InvokeStream invoke a python generator function, or any function returning an iterable, and deliver json of
+// every item as soon as python produces it. The items channel is closed after the last item, then the error channel
+// gets the exception of python, ErrTimeout or ErrCanceled if there is one, and is closed.
+//
+// Python waits while the items are not received and its output pipe is full. Cancel the context to stop
+// the generator before it ends, the python process is killed then. A stream always runs in a new python
+// process, the pool of runner is not used.
+func (r *Runner) InvokeStream(ctx context.Context, scriptPath string, funcName string, params []interface{}) (<-chan json.RawMessage, <-chan error) {
+	return r.stream(ctx, invocation{scriptPath: scriptPath, funcName: funcName, params: params})
+}
+
+// Stream is like DoContext for python generator functions, every item is decoded to the return type, see InvokeStream
+func (w *WrapInfo) Stream(ctx context.Context, interfaces ...interface{}) (<-chan interface{}, <-chan error) {
+	items := make(chan interface{})
+	errs := make(chan error, 1)
+
+	if err := w.ready(); err != nil {
+		close(items)
+		errs <- err
+		close(errs)
+		return items, errs
+	}
+
+	inv := w.invocation(w.withDefaults(w.params(interfaces)))
+
+	runner := w.runner
+	if runner == nil {
+		runner = DefaultRunner()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	raws, rawErrs := runner.stream(ctx, inv)
+	go func() {
+		defer close(errs)
+		defer cancel()
+
+		var err error
+		for raw := range raws {
+			i := reflect.New(w.returnType).Interface()
+			if err = Unmarshal(raw, i); err != nil {
+				break
+			}
+			select {
+			case items <- reflect.ValueOf(i).Elem().Interface():
+			case <-ctx.Done():
+			}
+		}
+		close(items)
+
+		if err != nil {
+			cancel()
+			for range raws {
+			}
+			<-rawErrs
+			errs <- err
+			return
+		}
+		if err := <-rawErrs; err != nil {
+			errs <- err
+		}
+	}()
+	return items, errs
+}
+
+// stream start python process iterating result of function and forward its items
+func (r *Runner) stream(ctx context.Context, inv invocation) (<-chan json.RawMessage, <-chan error) {
+	items := make(chan json.RawMessage)
+	errs := make(chan error, 1)
+	go func() {
+		err := r.doStream(ctx, items, inv)
+		close(items)
+		if err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+	return items, errs
+}
+
+// doStream run python and send items to channel until python exits or context is done
+func (r *Runner) doStream(ctx context.Context, items chan<- json.RawMessage, inv invocation) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	if err := inv.check(); err != nil {
+		return err
+	}
+
+	cmd := exec.Command(r.executable)
+	setProcessGroup(cmd)
+
+	channel, err := openResultChannel(cmd)
+	if err != nil {
+		return fmt.Errorf("invoke python function error: open result channel error: %v", err)
+	}
+
+	files := r.newPayloadFiles()
+	defer files.close()
+	files.firstFd = extraFilesFd + len(cmd.ExtraFiles)
+	tempScript, appendPythonPath, err := r.generateTempScript(PythonStreamTemplate, channel.fd, inv, files)
+	if err != nil {
+		channel.close()
+		return fmt.Errorf("invoke python function error: generate temp script error: %v", err)
+	}
+
+	r.setupCommand(cmd, appendPythonPath)
+	if err := files.attach(cmd, false); err != nil {
+		channel.close()
+		return err
+	}
+
+	if err := r.restrict(cmd); err != nil {
+		channel.close()
+		return err
+	}
+
+	callback, err := openCallbackChannel(cmd, r.callbacks)
+	if err != nil {
+		channel.close()
+		return fmt.Errorf("invoke python function error: open callback channel error: %v", err)
+	}
+
+	serr := bytes.Buffer{}
+	limited := newLimitedOutput(cmd, r.limits.OutputSize)
+	cmd.Stdin = strings.NewReader(tempScript)
+	cmd.Stderr = limited.writer(&serr)
+	reader, err := channel.streamReader(cmd)
+	if err != nil {
+		channel.close()
+		callback.close()
+		return fmt.Errorf("invoke python function error: %v", err)
+	}
+
+	if err := r.start(cmd); err != nil {
+		channel.close()
+		callback.close()
+		return fmt.Errorf("invoke python function error: %v", err)
+	}
+	channel.streamStarted()
+	callback.started(ctx)
+
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-stop:
+		}
+	}()
+
+	ended, exception := r.readStream(ctx, reader, items)
+	if channel.fd >= 0 {
+		reader.Close()
+	}
+	err = cmd.Wait()
+	callback.wait()
+	close(stop)
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return contextError(ctxErr)
+	}
+	if limitErr := r.exceeded(cmd, limited); limitErr != nil {
+		return limitErr
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return fmt.Errorf("invoke python function error: %v", err)
+	}
+	if exception != nil {
+		return r.limitError(exception)
+	}
+	if !ended {
+		// python exited before the end of items, for example a syntax error or os._exit()
+		if e := ParseTraceback(serr.String()); e != nil {
+			return r.limitError(e)
+		}
+		return fmt.Errorf("invoke python function error: python exited without result: %v\n%v", cmd.ProcessState, serr.String())
+	}
+	return nil
+}
+
+// readStream send items read from python until EOF, return if the end of items is read and the exception of python.
+// It stops reading when the context is done.
+func (r *Runner) readStream(ctx context.Context, reader io.Reader, items chan<- json.RawMessage) (bool, error) {
+	ended := false
+	var exception error
+	br := bufio.NewReader(reader)
+	for {
+		line, err := br.ReadString('\n')
+		message, ok, parseErr := r.parseStreamLine(line)
+		if parseErr != nil {
+			exception = parseErr
+		} else if message.End {
+			ended = true
+		} else if len(message.Exception) > 0 {
+			exception = exceptionError(string(message.Exception))
+		} else if ok {
+			select {
+			case items <- message.Item:
+			case <-ctx.Done():
+				return ended, exception
+			}
+		}
+		if err != nil {
+			return ended, exception
+		}
+	}
+}
+
+// streamMessage is one line python sends through the result channel of a stream, holding the json of an item, or
+// marking the end of items, or holding the json exception
+type streamMessage struct {
+	Item      json.RawMessage `json:"item"`
+	End       bool            `json:"end"`
+	Exception json.RawMessage `json:"exception"`
+}
+
+// parseStreamLine return the message in line and if there is one, a line is one json message through the result
+// channel, or holds sections between markers when it is stdout, where an empty item marks the end of items
+func (r *Runner) parseStreamLine(line string) (streamMessage, bool, error) {
+	if !resultChannelSupported {
+		if section, ok := sectionBetween(line, r.returnValueStart, r.returnValueEnd); ok {
+			return streamMessage{Item: json.RawMessage(section), End: len(section) < 1}, true, nil
+		}
+		if section, ok := sectionBetween(line, r.exceptionStart, r.exceptionEnd); ok {
+			return streamMessage{Exception: json.RawMessage(section)}, true, nil
+		}
+		return streamMessage{}, false, nil
+	}
+
+	line = strings.TrimSpace(line)
+	if len(line) < 1 {
+		return streamMessage{}, false, nil
+	}
+	var m streamMessage
+	if err := json.Unmarshal([]byte(line), &m); err != nil {
+		return m, false, fmt.Errorf("invoke python function error: unexpected stream message: %v: %v", err, line)
+	}
+	return m, true, nil
+}
+
+// sectionBetween return text between prefix and the last suffix after it, and if both are found
+func sectionBetween(str string, prefix string, suffix string) (string, bool) {
+	start := strings.Index(str, prefix)
+	if start < 0 {
+		return "", false
+	}
+	str = str[start+len(prefix):]
+	end := strings.LastIndex(str, suffix)
+	if end < 0 {
+		return "", false
+	}
+	return str[:end], true
+}
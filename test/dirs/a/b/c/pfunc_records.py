@@ -0,0 +1,120 @@
+import collections
+
+Point = collections.namedtuple("Point", ["x", "y"])
+
+
+class Account(object):
+    def __init__(self, owner, balance):
+        self.owner = owner
+        self.balance = balance
+        self.history = [Point(0, balance)]
+        self._secret = "hidden"
+
+
+class Pair(object):
+    __slots__ = ("left", "right")
+
+    def __init__(self, left, right):
+        self.left = left
+        self.right = right
+
+
+class Money(object):
+    def __init__(self, cents):
+        self.cents = cents
+
+    def __pfunc__(self):
+        return {"amount": self.cents / 100.0, "currency": "USD"}
+
+
+class Opaque(object):
+    def __init__(self):
+        self.value = 1
+
+
+class Person(object):
+    def __init__(self, name, age, full_name=None):
+        self.name = name
+        self.age = age
+        self.full_name = full_name
+
+    def greet(self):
+        return "hi " + self.name
+
+
+Team = collections.namedtuple("Team", ["leader", "members"])
+
+
+try:
+    import dataclasses
+    import enum
+
+    Status = enum.Enum("Status", "NEW PAID")
+    Color = enum.Enum("Color", [("RED", "red"), ("GREEN", "green")], type=str)
+    Order = dataclasses.make_dataclass("Order", [("id", int), ("status", Status), ("lines", list), ("color", Color)])
+    Card = dataclasses.make_dataclass("Card", [("title", str), ("tags", list)])
+except ImportError:
+    Status = Color = Order = Card = None
+
+
+def point(x, y):
+    return Point(x, y)
+
+
+def points():
+    return {"path": [Point(1, 2), Point(3, 4)], "origin": (Point(0, 0),)}
+
+
+def account(owner, balance):
+    return Account(owner, balance)
+
+
+def pair():
+    return Pair(Money(150), [Opaque()])
+
+
+def wallet():
+    return {"cash": Money(150), "other": [Opaque()]}
+
+
+def opaque():
+    return Opaque()
+
+
+def status(name):
+    return Status[name]
+
+
+def order():
+    return Order(7, Status.PAID, [Point(1, 1), Money(99)], Color.GREEN)
+
+
+def orders():
+    return [Status.NEW, Status.PAID, Color.RED]
+
+
+def walk(n):
+    for i in range(n):
+        yield Point(i, i * i)
+
+
+def encode_opaque(value):
+    if isinstance(value, Opaque):
+        return "opaque " + str(value.value)
+    return NotImplemented
+
+
+def describe_person(person):
+    return [type(person).__name__, person.greet(), person.age, person.full_name]
+
+
+def describe_team(team):
+    return [type(team).__name__, team.leader.greet(), [type(m).__name__ + " " + m.name for m in team.members]]
+
+
+def older(person, years):
+    return Person(person.name, person.age + years, person.full_name)
+
+
+def describe_card(card):
+    return [type(card).__name__, dataclasses.is_dataclass(card), card.title, card.tags]
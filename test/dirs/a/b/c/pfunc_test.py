@@ -1,6 +1,6 @@
 
 def do_print():
-    print "hello world"
+    print("hello world")
 
 
 def add(a, b):
@@ -12,7 +12,7 @@ def names_of_three_people(a, b, c):
 
 
 def divide(a, b):
-    return a / b
+    return a // b
 
 
 def float_divide(a, b):
@@ -34,3 +34,329 @@ def first_param_and_other_params(first, **other):
     total = other
     total['first'] = first
     return total
+
+
+calls = 0
+
+
+def count_calls():
+    global calls
+    calls += 1
+    return calls
+
+
+def crash(code):
+    import os
+    os._exit(code)
+
+
+def sleep(seconds):
+    import sys
+    import time
+    print("sleep started")
+    sys.stdout.flush()
+    time.sleep(seconds)
+
+
+def sleep_in_child(seconds):
+    import subprocess
+    import sys
+    import time
+    child = subprocess.Popen([sys.executable, "-c", "import time; time.sleep(%s)" % seconds])
+    print("child %s" % child.pid)
+    sys.stdout.flush()
+    time.sleep(seconds)
+
+
+def get_env(name):
+    import os
+    return os.environ.get(name)
+
+
+def get_cwd():
+    import os
+    return os.getcwd()
+
+
+def echo(value):
+    return value
+
+
+def type_name(value):
+    return type(value).__name__
+
+
+class PfuncTestError(Exception):
+    pass
+
+
+def raise_custom(code, reason):
+    raise PfuncTestError(code, reason)
+
+
+def raise_with_context():
+    try:
+        {}["missing"]
+    except KeyError:
+        raise ValueError("lookup failed")
+
+
+def raise_with_cause():
+    try:
+        {}["missing"]
+    except KeyError as e:
+        error = RuntimeError("wrapped")
+        error.__cause__ = e
+        raise error
+
+
+def spoof_result():
+    import sys
+    print("pfunc_return_start_666pfunc_return_end_")
+    sys.stdout.write("pfunc_exception_start_{}pfunc_exception_end_\n")
+    sys.stderr.write("a warning\n")
+    return 1
+
+
+def write_raw_output():
+    import os
+    os.write(1, "raw stdout\n".encode())
+    os.write(2, "raw stderr\n".encode())
+    return 2
+
+
+def count_to(n):
+    for i in range(n):
+        yield i
+
+
+def count_then_raise(n):
+    for i in range(n):
+        yield i
+    raise ValueError("count exhausted")
+
+
+def count_forever():
+    i = 0
+    while True:
+        yield i
+        i += 1
+
+
+def yield_then_sleep(seconds):
+    import time
+    yield "first"
+    time.sleep(seconds)
+    yield "second"
+
+
+def people_stream(names):
+    for name in names:
+        print("producing " + name)
+        yield {"name": name, "age": len(name), "hobbies": []}
+
+
+def squares(n):
+    return [i * i for i in range(n)]
+
+
+def rank_users(ids):
+    import pfunc_runtime
+    users = [pfunc_runtime.call("lookup_user", i) for i in ids]
+    return [u["name"] for u in sorted(users, key=lambda u: -u["score"])]
+
+
+def call_callback(name, *args):
+    import pfunc_runtime
+    return pfunc_runtime.call(name, *args)
+
+
+def callback_error_type(name, *args):
+    import pfunc_runtime
+    try:
+        pfunc_runtime.call(name, *args)
+    except pfunc_runtime.CallbackError as e:
+        return [type(e).__name__, str(e)]
+    return None
+
+
+def stream_callbacks(n):
+    import pfunc_runtime
+    for i in range(n):
+        yield pfunc_runtime.call("double", i)
+
+
+class Model(object):
+    class Error(Exception):
+        pass
+
+    class Stats(object):
+        def __init__(self, predictions):
+            self.predictions = predictions
+
+    def __init__(self, name, weight=1):
+        self.name = name
+        self.weight = weight
+        self.predictions = 0
+
+    def predict(self, x):
+        self.predictions += 1
+        return x * self.weight
+
+    def scale(self, factor=2):
+        return Model(self.name + "_scaled", self.weight * factor)
+
+    def itself(self):
+        return self
+
+    def fail(self):
+        raise ValueError("model failed")
+
+    def stats(self):
+        return Model.Stats(self.predictions)
+
+    def fail_nested(self):
+        raise Model.Error("model failed")
+
+
+def make_function(n):
+    return lambda: n
+
+
+def load_model(name, weight):
+    return Model(name, weight)
+
+
+class Greeter(object):
+    default_greeting = "Hello"
+
+    class Formats(object):
+        @staticmethod
+        def shout(text):
+            return text.upper() + "!"
+
+    def __init__(self, name, greeting=None):
+        self.name = name
+        self.greeting = greeting or Greeter.default_greeting
+
+    def greet(self, other, punctuation="."):
+        return "{0} {1}, I am {2}{3}".format(self.greeting, other, self.name, punctuation)
+
+    def count(self, n):
+        for i in range(n):
+            yield self.name + str(i)
+
+    @staticmethod
+    def static_join(a, b):
+        return a + "-" + b
+
+    @classmethod
+    def create(cls, name):
+        return cls(name).greet("you")
+
+
+class _Helpers(object):
+    @staticmethod
+    def normalize(values):
+        total = float(sum(values))
+        return [v / total for v in values]
+
+
+helpers = _Helpers()
+
+
+def process_id(value, seconds=0):
+    import os
+    import time
+    time.sleep(seconds)
+    print("value " + str(value))
+    return [os.getpid(), value]
+
+
+def burn_cpu():
+    while True:
+        pass
+
+
+def allocate(megabytes):
+    return len(bytearray(megabytes * 1024 * 1024))
+
+
+def open_files(n):
+    files = [open(__file__) for i in range(n)]
+    return len(files)
+
+
+def print_lots(n):
+    print("x" * n)
+
+
+def write_file(path):
+    f = open(path, "w")
+    f.write("pfunc")
+    f.close()
+    return path
+
+
+def connect(port):
+    import socket
+    socket.create_connection(("127.0.0.1", port), 2).close()
+    return True
+
+
+def resolve(host):
+    import socket
+    return socket.getaddrinfo(host, 80)[0][4][0]
+
+
+def sum_and_size(values):
+    return [sum(values), len(values)]
+
+
+def make_list(n):
+    return list(range(n))
+
+
+def python_values():
+    import datetime
+    import decimal
+    return {
+        "aware": datetime.datetime(2020, 1, 2, 3, 4, 5, 123456, tzinfo=pfunc_utc_plus(480)),
+        "naive": datetime.datetime(2020, 1, 2, 3, 4, 5),
+        "date": datetime.date(2021, 12, 31),
+        "delta": datetime.timedelta(days=1, seconds=2, microseconds=3),
+        "bytes": bytearray(b"\x00\xffpfunc"),
+        "decimal": decimal.Decimal("3.141592653589793238462643383279"),
+        "set": set([3, 1, 2]),
+        "tuple": (1, "two"),
+        "complex": complex(1.5, -2),
+        "big": 2 ** 100,
+    }
+
+
+def pfunc_utc_plus(minutes):
+    import datetime
+
+    class Offset(datetime.tzinfo):
+        def utcoffset(self, dt):
+            return datetime.timedelta(minutes=minutes)
+
+        def dst(self, dt):
+            return datetime.timedelta(0)
+
+    return Offset()
+
+
+def describe(value):
+    import datetime
+    if isinstance(value, datetime.datetime):
+        offset = value.utcoffset()
+        return [type(value).__name__, value.strftime("%Y-%m-%d %H:%M:%S.%f"), offset.total_seconds() if offset is not None else None]
+    if isinstance(value, (bytes, bytearray)):
+        return [type(value).__name__, list(bytearray(value))]
+    return [type(value).__name__, str(value)]
+
+
+def imported_who(module):
+    import importlib
+    return importlib.import_module(module).who()
@@ -0,0 +1,81 @@
+from dataclasses import dataclass
+from typing import Dict, List, Optional, Tuple, TypedDict
+
+
+class Point(TypedDict):
+    x: float
+    y: float
+
+
+@dataclass
+class Employee:
+    """An employee of a company."""
+    name: str
+    age: int
+    skills: List[str]
+    manager: Optional[str] = None
+    active: bool = True
+    level: int = 5
+
+
+def distance(a: Point, b: Point) -> float:
+    """Euclidean distance between two points."""
+    return ((a["x"] - b["x"]) ** 2 + (a["y"] - b["y"]) ** 2) ** 0.5
+
+
+def middle(a: Point, b: Point) -> Point:
+    return {"x": (a["x"] + b["x"]) / 2, "y": (a["y"] + b["y"]) / 2}
+
+
+def greet(name: str, greeting: str = "Hello", punctuation: str = "!") -> str:
+    """Greet someone.
+
+    The greeting and punctuation can be changed.
+    """
+    return greeting + ", " + name + punctuation
+
+
+def total(*values: int) -> int:
+    return sum(values)
+
+
+def labels(prefix: str, *, separator: str, **pairs: str) -> List[str]:
+    return sorted(prefix + k + separator + v for k, v in pairs.items())
+
+
+def find(scores: Dict[str, int], key: str) -> Optional[int]:
+    return scores.get(key)
+
+
+def bounds(values: List[float]) -> Tuple[float, ...]:
+    return min(values), max(values)
+
+
+def untyped(a, b=2):
+    return a + b
+
+
+def hire(name: str, age: int) -> Employee:
+    return Employee(name, age, [])
+
+
+def promote(employee: Employee) -> Employee:
+    e = Employee(**employee)
+    e.level += 1
+    return e
+
+
+def nothing(type: str) -> None:
+    pass
+
+
+def clamp(value: int, low: int = 0, high: int = 10, /) -> int:
+    return max(low, min(high, value))
+
+
+def größe(breite: int, höhe: int) -> int:
+    return breite * höhe
+
+
+def _private():
+    pass
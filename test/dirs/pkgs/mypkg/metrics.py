@@ -0,0 +1,10 @@
+def score(values, weight=1):
+    return sum(values) * weight
+
+
+class Scorer(object):
+    def __init__(self, weight):
+        self.weight = weight
+
+    def score(self, values):
+        return score(values, self.weight)
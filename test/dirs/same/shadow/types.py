@@ -0,0 +1,5 @@
+# named like the standard types module
+
+
+def who():
+    return "shadow"
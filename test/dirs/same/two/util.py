@@ -0,0 +1,2 @@
+def who():
+    return "two"
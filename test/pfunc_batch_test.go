@@ -0,0 +1,135 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvokeBatch(t *testing.T) {
+	results := pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "divide", [][]interface{}{{6, 3}, {1, 0}, {9, 3}})
+	assert.Equal(t, 3, len(results))
+
+	assert.Equal(t, true, results[0].NoError)
+	assert.Equal(t, 2, results[0].MustInt())
+
+	assert.Equal(t, false, results[1].NoError)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(results[1].Exception, &pe))
+	assert.Equal(t, "ZeroDivisionError", pe.Type)
+
+	assert.Equal(t, true, results[2].NoError)
+	assert.Equal(t, 3, results[2].MustInt())
+	assert.Equal(t, results[0].TempScript, results[2].TempScript)
+
+	assert.Equal(t, 0, len(pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "divide", nil)))
+}
+
+func TestInvokeBatchImportsOnce(t *testing.T) {
+	var paramsList [][]interface{}
+	for i := 0; i < 200; i++ {
+		paramsList = append(paramsList, []interface{}{})
+	}
+	results := pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "count_calls", paramsList)
+	for i, result := range results {
+		assert.Equal(t, true, result.NoError)
+		assert.Equal(t, i+1, result.MustInt())
+	}
+}
+
+func TestInvokeBatchOutputAndUnserializableResult(t *testing.T) {
+	results := pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "do_print", [][]interface{}{{}, {}})
+	for _, result := range results {
+		assert.Equal(t, true, result.NoError)
+		assert.Equal(t, "hello world\n", result.Output)
+	}
+
+	results = pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "make_function", [][]interface{}{{1}, {}})
+	assert.Equal(t, false, results[0].NoError)
+	assert.Contains(t, results[0].Exception.Error(), "JSON serializable")
+	assert.Equal(t, false, results[1].NoError)
+	assert.Contains(t, results[1].Exception.Error(), "TypeError")
+}
+
+func TestInvokeBatchProcessFailure(t *testing.T) {
+	results := pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "function_not_exists", [][]interface{}{{}, {}})
+	for _, result := range results {
+		assert.Equal(t, false, result.NoError)
+		assert.Contains(t, result.Exception.Error(), "ImportError")
+	}
+
+	results = pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "crash", [][]interface{}{{0}, {1}})
+	for _, result := range results {
+		assert.Equal(t, false, result.NoError)
+		assert.Contains(t, result.Exception.Error(), "python exited without result")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	results = pfunc.DefaultRunner().InvokeBatchContext(ctx, "dirs/a/b/c/pfunc_test.py", "sleep", [][]interface{}{{5}, {5}})
+	for _, result := range results {
+		assert.Equal(t, pfunc.ErrTimeout, result.Exception)
+	}
+}
+
+func TestDoBatch(t *testing.T) {
+	values, errs := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Return(0).ParamDefaults(10, 2).
+		DoBatch([][]interface{}{{6, 3}, {1, 0}, {}, {8}})
+	assert.Equal(t, []interface{}{2, 0, 5, 4}, values)
+	assert.Nil(t, errs[0])
+	assert.NotNil(t, errs[1])
+	assert.Nil(t, errs[2])
+	assert.Nil(t, errs[3])
+
+	greeter := pfunc.Class("dirs/a/b/c/pfunc_test.py", "Greeter").New("Bob").Method("greet").Return("").KeyWrodParam("punctuation", "!")
+	values, errs = greeter.DoBatch([][]interface{}{{"Alice"}, {"Eve"}})
+	assert.Equal(t, []interface{}{"Hello Alice, I am Bob!", "Hello Eve, I am Bob!"}, values)
+	assert.Equal(t, []error{nil, nil}, errs)
+
+	_, errs = pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").DoBatch([][]interface{}{{1, 1}})
+	assert.NotNil(t, errs[0])
+}
+
+func TestDoBatchInPool(t *testing.T) {
+	p, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	values, errs := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Return(0).Pool(p).DoBatch([][]interface{}{{6, 3}, {1, 0}})
+	assert.Equal(t, []interface{}{2, 0}, values)
+	assert.Nil(t, errs[0])
+	assert.NotNil(t, errs[1])
+}
+
+func TestInvokeBatchInPoolIsOneRequest(t *testing.T) {
+	p, err := pfunc.NewPool(2)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	r := pfunc.DefaultRunner().With(pfunc.WithPool(p))
+	results := r.InvokeBatch("dirs/a/b/c/pfunc_test.py", "process_id", [][]interface{}{{1}, {2}, {3}})
+	var pids []interface{}
+	for i, result := range results {
+		var pidAndValue []interface{}
+		assert.Equal(t, true, result.NoError, result.Inspect())
+		assert.Nil(t, result.Decode(&pidAndValue))
+		assert.Equal(t, float64(i+1), pidAndValue[1])
+		pids = append(pids, pidAndValue[0])
+	}
+	assert.Equal(t, []interface{}{pids[0], pids[0], pids[0]}, pids)
+
+	results = r.InvokeBatch("dirs/a/b/c/pfunc_test.py", "do_print", [][]interface{}{{}, {1}})
+	assert.Equal(t, "hello world\n", results[0].Output)
+	assert.Equal(t, false, results[1].NoError)
+	assert.Contains(t, results[1].Exception.Error(), "TypeError")
+
+	results = r.InvokeBatch("dirs/a/b/c/pfunc_test.py", "function_not_exists", [][]interface{}{{}, {}})
+	for _, result := range results {
+		assert.Equal(t, false, result.NoError)
+		assert.Contains(t, result.Exception.Error(), "function_not_exists")
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+var bindDivide func(a, b int) (int, error)
+var bindGetPerson func(name string, age int, hobby1 string, hobby2 string) (Person, error)
+var bindNamesOfThreePeople func(ps ...Person) (string, error)
+var bindSleep func(ctx context.Context, seconds int) error
+var bindAdd func(a, b float64) float64
+var bindDoPrint func() error
+
+func TestBind(t *testing.T) {
+	assert.Nil(t, pfunc.Bind(&bindDivide, "dirs/a/b/c/pfunc_test.py", "divide"))
+
+	i, err := bindDivide(6, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, i)
+
+	i, err = bindDivide(6, 0)
+	fmt.Println(err)
+	assert.Equal(t, 0, i)
+	assert.Contains(t, err.Error(), "by zero")
+}
+
+func TestBindReturnStruct(t *testing.T) {
+	assert.Nil(t, pfunc.Bind(&bindGetPerson, "dirs/a/b/c/pfunc_test.py", "func_return_struct"))
+
+	person, err := bindGetPerson("Tom", 33, "Football", "Shopping")
+	assert.Nil(t, err)
+	assert.Equal(t, Person{Name: "Tom", Age: 33, Hobby: []string{"Football", "Shopping"}}, person)
+}
+
+func TestBindVariadic(t *testing.T) {
+	assert.Nil(t, pfunc.Bind(&bindNamesOfThreePeople, "dirs/a/b/c/pfunc_test.py", "names_of_three_people"))
+
+	names, err := bindNamesOfThreePeople(Person{Name: "Tom"}, Person{Name: "Jack"}, Person{Name: "Lily"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Tom and Jack and Lily", names)
+
+	names, err = bindNamesOfThreePeople([]Person{{Name: "A"}, {Name: "B"}, {Name: "C"}}...)
+	assert.Nil(t, err)
+	assert.Equal(t, "A and B and C", names)
+
+	_, err = bindNamesOfThreePeople(Person{Name: "Tom"})
+	assert.Contains(t, err.Error(), "TypeError")
+}
+
+func TestBindContext(t *testing.T) {
+	assert.Nil(t, pfunc.Bind(&bindSleep, "dirs/a/b/c/pfunc_test.py", "sleep"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, pfunc.ErrTimeout, bindSleep(ctx, 30))
+	assert.Nil(t, bindSleep(context.Background(), 0))
+}
+
+func TestBindWithoutError(t *testing.T) {
+	assert.Nil(t, pfunc.Bind(&bindAdd, "dirs/a/b/c/pfunc_test.py", "add"))
+	assert.Equal(t, 3.5, bindAdd(1, 2.5))
+
+	assert.Nil(t, pfunc.Bind(&bindAdd, "dirs/a/b/c/pfunc_test.py", "not_exists"))
+	assert.Panics(t, func() { bindAdd(1, 2) })
+
+	assert.Nil(t, pfunc.Bind(&bindDoPrint, "dirs/a/b/c/pfunc_test.py", "do_print"))
+	assert.Nil(t, bindDoPrint())
+}
+
+func TestBindWithRunner(t *testing.T) {
+	r := pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()))
+	var add func(a, b int) (int, error)
+	assert.Nil(t, r.Bind(&add, "dirs/a/b/c/pfunc_test.py", "add"))
+
+	i, err := add(1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, i)
+}
+
+func TestBindInvalidTarget(t *testing.T) {
+	var f func(a int) (int, error)
+	assert.NotNil(t, pfunc.Bind(f, "dirs/a/b/c/pfunc_test.py", "add"))
+	assert.NotNil(t, pfunc.Bind(new(int), "dirs/a/b/c/pfunc_test.py", "add"))
+
+	var twoValues func() (int, int)
+	assert.NotNil(t, pfunc.Bind(&twoValues, "dirs/a/b/c/pfunc_test.py", "add"))
+
+	var errorFirst func() (error, int)
+	assert.NotNil(t, pfunc.Bind(&errorFirst, "dirs/a/b/c/pfunc_test.py", "add"))
+
+	var threeResults func() (int, int, error)
+	assert.NotNil(t, pfunc.Bind(&threeResults, "dirs/a/b/c/pfunc_test.py", "add"))
+}
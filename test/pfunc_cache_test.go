@@ -0,0 +1,214 @@
+package test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheRunner(t *testing.T) {
+	r := pfunc.DefaultRunner().With(pfunc.WithCache(pfunc.NewMemoryCache(10, 0)))
+	first := r.Call("dirs/a/b/c/pfunc_test.py", "process_id", 1)
+	assert.Equal(t, true, first.NoError)
+
+	second := r.Call("dirs/a/b/c/pfunc_test.py", "process_id", 1)
+	assert.Equal(t, true, second.NoError)
+	assert.Equal(t, first.JsonRepresentation, second.JsonRepresentation)
+	assert.Equal(t, "value 1\n", second.Output)
+	assert.Equal(t, "", second.Exception.Error())
+
+	other := r.Call("dirs/a/b/c/pfunc_test.py", "process_id", 2)
+	assert.NotEqual(t, first.JsonRepresentation, other.JsonRepresentation)
+
+	uncached := pfunc.Call("dirs/a/b/c/pfunc_test.py", "process_id", 1)
+	assert.NotEqual(t, first.JsonRepresentation, uncached.JsonRepresentation)
+}
+
+func TestCacheErrorsAreNotCached(t *testing.T) {
+	r := pfunc.DefaultRunner().With(pfunc.WithCache(pfunc.NewMemoryCache(10, 0)))
+	first := r.Call("dirs/a/b/c/pfunc_test.py", "raise_custom", 1, "no")
+	second := r.Call("dirs/a/b/c/pfunc_test.py", "raise_custom", 1, "no")
+	assert.Equal(t, false, first.NoError)
+	assert.Equal(t, false, second.NoError)
+}
+
+func TestCacheWrapInfo(t *testing.T) {
+	cache := pfunc.NewMemoryCache(1, 0)
+	processID := pfunc.Func("dirs/a/b/c/pfunc_test.py", "process_id").Return([]int{}).Cache(cache)
+	first, err := processID.Params(1).Do()
+	assert.Nil(t, err)
+	second, err := processID.Params(1).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	// the least recently used result is dropped
+	_, err = processID.Params(2).Do()
+	assert.Nil(t, err)
+	third, err := processID.Params(1).Do()
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, third)
+}
+
+func TestCacheTTL(t *testing.T) {
+	processID := pfunc.Func("dirs/a/b/c/pfunc_test.py", "process_id").Return([]int{}).
+		Cache(pfunc.NewMemoryCache(0, 300*time.Millisecond)).Prepare()
+	first, _ := processID.Call(1)
+	second, _ := processID.Call(1)
+	assert.Equal(t, first, second)
+
+	time.Sleep(400 * time.Millisecond)
+	third, _ := processID.Call(1)
+	assert.NotEqual(t, first, third)
+}
+
+func TestCacheScriptChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pfunc_cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "version.py")
+	assert.Nil(t, ioutil.WriteFile(script, []byte("def version():\n    return 1\n"), 0644))
+
+	version := pfunc.Func(script, "version").Return(0).Cache(pfunc.NewMemoryCache(10, 0)).Prepare()
+	result, err := version.Call()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result)
+
+	assert.Nil(t, ioutil.WriteFile(script, []byte("def version():\n    return 2\n"), 0644))
+	result, err = version.Call()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result)
+}
+
+func TestCacheScriptChangedInPool(t *testing.T) {
+	p, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+	dir, err := ioutil.TempDir("", "pfunc_cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "version.py")
+	assert.Nil(t, ioutil.WriteFile(script, []byte("def version():\n    return 1\n"), 0644))
+
+	cache := pfunc.NewMemoryCache(10, 0)
+	version := pfunc.Func(script, "version").Return(0).Pool(p).Cache(cache).Prepare()
+	result, err := version.Call()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result)
+
+	// the worker loads the script again instead of running the module it loaded before
+	assert.Nil(t, ioutil.WriteFile(script, []byte("def version():\n    return 2\n"), 0644))
+	result, err = version.Call()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result)
+	assert.Equal(t, 2, p.Call(script, "version").MustInt())
+
+	assert.Nil(t, ioutil.WriteFile(script, []byte("def version():\n    return 3\n"), 0644))
+	assert.Equal(t, 3, p.Call(script, "version").MustInt())
+	result, err = version.Call()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result)
+}
+
+func TestCacheDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pfunc_cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	cache, err := pfunc.NewDiskCache(filepath.Join(dir, "results"), 0)
+	assert.Nil(t, err)
+	first, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "process_id").Return([]int{}).Cache(cache).Prepare().Call(1)
+	assert.Nil(t, err)
+
+	// a new cache of the same directory, like one made by another program
+	cache, err = pfunc.NewDiskCache(filepath.Join(dir, "results"), 0)
+	assert.Nil(t, err)
+	second, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "process_id").Return([]int{}).Cache(cache).Prepare().Call(1)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	cache, err = pfunc.NewDiskCache(filepath.Join(dir, "results"), time.Nanosecond)
+	assert.Nil(t, err)
+	third, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "process_id").Return([]int{}).Cache(cache).Prepare().Call(1)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, third)
+}
+
+func TestCacheConcurrentCallsShareInvocation(t *testing.T) {
+	processID := pfunc.Func("dirs/a/b/c/pfunc_test.py", "process_id").Return([]int{}).
+		Cache(pfunc.NewMemoryCache(10, 0)).Prepare()
+	results := make([]interface{}, 5)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := processID.Call(3, 1)
+			assert.Nil(t, err)
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+	for _, result := range results {
+		assert.Equal(t, results[0], result)
+	}
+}
+
+func TestCacheCanceledCallDoesNotFailOthers(t *testing.T) {
+	processID := pfunc.Func("dirs/a/b/c/pfunc_test.py", "process_id").Return([]int{}).
+		Cache(pfunc.NewMemoryCache(10, 0)).Prepare()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	var err error
+	done := make(chan struct{})
+	go func() {
+		_, err = processID.CallContext(ctx, 4, 1)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	result, otherErr := processID.Call(4, 1)
+	<-done
+	assert.Equal(t, pfunc.ErrTimeout, err)
+	assert.Nil(t, otherErr)
+	assert.Equal(t, 2, len(result.([]int)))
+}
+
+func TestCacheKeyedByProcessConfig(t *testing.T) {
+	cache := pfunc.NewMemoryCache(10, 0)
+	one, err := filepath.Abs("dirs/same/one")
+	assert.Nil(t, err)
+	two, err := filepath.Abs("dirs/same/two")
+	assert.Nil(t, err)
+
+	// runners importing different modules of the same name do not share results
+	r := pfunc.DefaultRunner().With(pfunc.WithCache(cache))
+	assert.Equal(t, "one", r.With(pfunc.WithSysPathPrepend(one)).Call("dirs/a/b/c/pfunc_test.py", "imported_who", "util").MustString())
+	assert.Equal(t, "two", r.With(pfunc.WithSysPathPrepend(two)).Call("dirs/a/b/c/pfunc_test.py", "imported_who", "util").MustString())
+	assert.Equal(t, "two", r.With(pfunc.WithPythonPaths(two)).Call("dirs/a/b/c/pfunc_test.py", "imported_who", "util").MustString())
+	assert.Equal(t, "one", r.With(pfunc.WithSysPathPrepend(one)).Call("dirs/a/b/c/pfunc_test.py", "imported_who", "util").MustString())
+}
+
+func TestCacheConcurrentCallsOfOtherCaches(t *testing.T) {
+	results := make([]interface{}, 2)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			processID := pfunc.Func("dirs/a/b/c/pfunc_test.py", "process_id").Return([]int{}).
+				Cache(pfunc.NewMemoryCache(10, 0)).Prepare()
+			result, err := processID.Call(5, 1)
+			assert.Nil(t, err)
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+	assert.NotEqual(t, results[0], results[1])
+}
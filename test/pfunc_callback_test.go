@@ -0,0 +1,155 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+type callbackUser struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+func newCallbackRunner(t *testing.T) *pfunc.Runner {
+	r := pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()))
+	users := map[int]callbackUser{1: {"Bob", 10}, 2: {"Alice", 30}, 3: {"Eve", 20}}
+	assert.Nil(t, r.RegisterCallback("lookup_user", func(ctx context.Context, id int) (callbackUser, error) {
+		u, ok := users[id]
+		if !ok {
+			return u, fmt.Errorf("user %v not found", id)
+		}
+		return u, nil
+	}))
+	assert.Nil(t, r.RegisterCallback("sum", func(values ...float64) float64 {
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		return total
+	}))
+	assert.Nil(t, r.RegisterCallback("double", func(i int) int {
+		return i * 2
+	}))
+	assert.Nil(t, r.RegisterCallback("nothing", func() {}))
+	assert.Nil(t, r.RegisterCallback("panic", func() error {
+		panic("boom")
+	}))
+	return r
+}
+
+func TestCallback(t *testing.T) {
+	r := newCallbackRunner(t)
+
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "rank_users", []int{1, 2, 3})
+	assert.Equal(t, true, result.NoError, result.Exception.Error())
+	assert.Equal(t, `["Alice", "Eve", "Bob"]`, result.JsonRepresentation)
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "call_callback", "sum", 1, 2.5, 3)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "6.5", result.JsonRepresentation)
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "call_callback", "nothing")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "null", result.JsonRepresentation)
+}
+
+func TestCallbackError(t *testing.T) {
+	r := newCallbackRunner(t)
+
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "call_callback", "lookup_user", 4)
+	assert.Equal(t, false, result.NoError)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "CallbackError", pe.Type)
+	assert.Equal(t, "pfunc_runtime.CallbackError", pe.QualifiedType())
+	assert.Equal(t, "user 4 not found", pe.Message)
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "callback_error_type", "panic")
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, result.JsonRepresentation, "boom")
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "callback_error_type", "missing")
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, result.JsonRepresentation, "not registered")
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "callback_error_type", "double", "a")
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, result.JsonRepresentation, "argument 0")
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "callback_error_type", "double", 1, 2)
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, result.JsonRepresentation, "takes 1 arguments, 2 given")
+}
+
+func TestCallbackNotRegistered(t *testing.T) {
+	r := pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "callback_error_type", "double", 1)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, `["CallbackError", "no go callback is registered"]`, result.JsonRepresentation)
+}
+
+func TestRegisterInvalidCallback(t *testing.T) {
+	r := pfunc.NewRunner()
+	assert.NotNil(t, r.RegisterCallback("a", 1))
+	assert.NotNil(t, r.RegisterCallback("b", func() (int, int) { return 0, 0 }))
+	assert.NotNil(t, r.RegisterCallback("c", func() (int, int, error) { return 0, 0, nil }))
+	var nilFunc func()
+	assert.NotNil(t, r.RegisterCallback("d", nilFunc))
+}
+
+func TestCallbackSharedByCopies(t *testing.T) {
+	r := pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()))
+	c := r.With(pfunc.WithWorkDir("."))
+	assert.Nil(t, r.RegisterCallback("double", func(i int) int { return i * 2 }))
+
+	result := c.Call("dirs/a/b/c/pfunc_test.py", "call_callback", "double", 21)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 42, result.MustInt())
+}
+
+func TestCallbackInPool(t *testing.T) {
+	r := newCallbackRunner(t)
+	p, err := r.NewPool(2)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	var calls int32
+	assert.Nil(t, r.RegisterCallback("count", func() int32 {
+		return atomic.AddInt32(&calls, 1)
+	}))
+
+	for i := 0; i < 3; i++ {
+		result := p.Call("dirs/a/b/c/pfunc_test.py", "rank_users", []int{3, 1})
+		assert.Equal(t, true, result.NoError)
+		assert.Equal(t, `["Eve", "Bob"]`, result.JsonRepresentation)
+	}
+
+	result := p.Call("dirs/a/b/c/pfunc_test.py", "call_callback", "count")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "1", result.JsonRepresentation)
+
+	result = p.Call("dirs/a/b/c/pfunc_test.py", "call_callback", "lookup_user", 4)
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "user 4 not found")
+}
+
+func TestCallbackInStream(t *testing.T) {
+	r := newCallbackRunner(t)
+	items, errs := r.InvokeStream(context.Background(), "dirs/a/b/c/pfunc_test.py", "stream_callbacks", []interface{}{3})
+
+	var got []int
+	for item := range items {
+		var i int
+		assert.Nil(t, json.Unmarshal(item, &i))
+		got = append(got, i)
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []int{0, 2, 4}, got)
+}
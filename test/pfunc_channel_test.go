@@ -0,0 +1,72 @@
+package test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserOutputCanNotSpoofResult(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("results are sent through stdout on windows")
+	}
+
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "spoof_result")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 1, result.MustInt())
+	assert.Contains(t, result.Output, "pfunc_return_start_666pfunc_return_end_")
+	assert.Contains(t, result.Output, "a warning")
+}
+
+func TestUserRawOutput(t *testing.T) {
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "write_raw_output")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 2, result.MustInt())
+	assert.Contains(t, result.Output, "raw stdout")
+	assert.Contains(t, result.Output, "raw stderr")
+}
+
+func TestUserOutputInPool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("results are sent through stdout on windows")
+	}
+
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	for i := 0; i < 3; i++ {
+		result := pool.Call("dirs/a/b/c/pfunc_test.py", "write_raw_output")
+		assert.Equal(t, true, result.NoError)
+		assert.Equal(t, 2, result.MustInt())
+
+		result = pool.Call("dirs/a/b/c/pfunc_test.py", "spoof_result")
+		assert.Equal(t, true, result.NoError)
+		assert.Equal(t, 1, result.MustInt())
+		assert.Contains(t, result.Output, "a warning")
+	}
+}
+
+func TestPythonExitedWithoutResult(t *testing.T) {
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "crash", 3)
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "python exited without result")
+	assert.Contains(t, result.Exception.Error(), "exit status 3")
+}
+
+func TestResultContainingMarkers(t *testing.T) {
+	values := []string{"x pfunc_return_end_ y", "a pfunc_exception_start_boom pfunc_exception_end_ b"}
+	for _, v := range values {
+		result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "echo", v)
+		assert.Equal(t, true, result.NoError, result.Inspect())
+		assert.Equal(t, v, result.MustString())
+	}
+
+	paramsList := [][]interface{}{{values[0]}, {values[1]}}
+	for i, result := range pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "echo", paramsList) {
+		assert.Equal(t, true, result.NoError, result.Inspect())
+		assert.Equal(t, values[i], result.MustString())
+	}
+}
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDottedFunctionName(t *testing.T) {
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "Greeter.static_join", "a", "b")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "a-b", result.MustString())
+
+	result = pfunc.Call("dirs/a/b/c/pfunc_test.py", "Greeter.create", "Bob")
+	assert.Equal(t, "Hello you, I am Bob.", result.MustString())
+
+	result = pfunc.Call("dirs/a/b/c/pfunc_test.py", "Greeter.Formats.shout", "hi")
+	assert.Equal(t, "HI!", result.MustString())
+
+	var normalized []float64
+	i, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "helpers.normalize").Return(normalized).Params([]int{1, 3}).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{0.25, 0.75}, i)
+
+	result = pfunc.Call("dirs/a/b/c/pfunc_test.py", "Greeter.missing")
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "AttributeError")
+}
+
+func TestInvalidFunctionName(t *testing.T) {
+	for _, name := range []string{"", "a..b", "a.", "os.system('ls')", "1a", "a b"} {
+		result := pfunc.Call("dirs/a/b/c/pfunc_test.py", name)
+		assert.Equal(t, false, result.NoError)
+		assert.Contains(t, result.Exception.Error(), "invalid python function name")
+	}
+}
+
+func TestDottedFunctionNameInPool(t *testing.T) {
+	p, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	result := p.Call("dirs/a/b/c/pfunc_test.py", "Greeter.Formats.shout", "hi")
+	assert.Equal(t, "HI!", result.MustString())
+
+	result = p.Call("dirs/a/b/c/pfunc_test.py", "a..b")
+	assert.Contains(t, result.Exception.Error(), "invalid python function name")
+}
+
+func TestClass(t *testing.T) {
+	greeter := pfunc.Class("dirs/a/b/c/pfunc_test.py", "Greeter").New("Bob")
+
+	result := greeter.Call("greet", "Alice")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "Hello Alice, I am Bob.", result.MustString())
+
+	greeter.NewKeyword("greeting", "Hi")
+	s, err := greeter.Method("greet").Return("").Params("Alice").KeyWrodParam("punctuation", "!").Do()
+	assert.Nil(t, err)
+	assert.Equal(t, "Hi Alice, I am Bob!", s)
+
+	result = greeter.Call("static_join", "a", "b")
+	assert.Equal(t, "a-b", result.MustString())
+
+	result = greeter.Call("missing")
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "AttributeError")
+
+	result = pfunc.Class("dirs/a/b/c/pfunc_test.py", "Greeter").Call("greet", "Alice")
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "TypeError")
+
+	result = greeter.Call("greet()")
+	assert.Contains(t, result.Exception.Error(), "invalid python method name")
+}
+
+func TestClassStream(t *testing.T) {
+	items, errs := pfunc.Class("dirs/a/b/c/pfunc_test.py", "Greeter").New("n").Method("count").Return("").Params(3).Stream(context.Background())
+	var got []string
+	for item := range items {
+		got = append(got, item.(string))
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []string{"n0", "n1", "n2"}, got)
+}
+
+func TestClassInPool(t *testing.T) {
+	p, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	greeter := pfunc.Class("dirs/a/b/c/pfunc_test.py", "Greeter").New("Bob").NewKeyword("greeting", "Hey").Pool(p)
+	result := greeter.Call("greet", "Alice")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "Hey Alice, I am Bob.", result.MustString())
+
+	o, err := greeter.Object(context.Background())
+	assert.Nil(t, err)
+	defer o.Release()
+	assert.Equal(t, "pfunc_test.Greeter", o.Type())
+	assert.Equal(t, "Hey Eve, I am Bob.", o.Call("greet", "Eve").MustString())
+	assert.Equal(t, "HI!", o.Call("Formats.shout", "hi").MustString())
+	assert.Equal(t, "Hello", o.GetAttr("default_greeting").MustString())
+
+	_, err = pfunc.Class("dirs/a/b/c/pfunc_test.py", "Greeter").Object(context.Background())
+	assert.NotNil(t, err)
+}
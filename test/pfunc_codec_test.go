@@ -0,0 +1,217 @@
+package test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+type Event struct {
+	Name     string
+	At       time.Time     `json:"at"`
+	Duration time.Duration `json:"duration"`
+	Data     []byte        `json:"data,omitempty"`
+	Price    *big.Float    `json:"price"`
+	Phase    complex128    `json:"phase"`
+	Extra    interface{}   `json:"extra"`
+}
+
+func TestCodecParamTypes(t *testing.T) {
+	v, err := pfunc.ProbePythonVersion(pfunc.GetPythonExecutable())
+	assert.Nil(t, err)
+	bytesName := "bytes"
+	if v == pfunc.Python2 {
+		bytesName = "str"
+	}
+
+	zone := time.FixedZone("", 8*3600)
+	price, _ := new(big.Float).SetPrec(200).SetString("12345678901234567890.125")
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	cases := []struct {
+		param    interface{}
+		expected []interface{}
+	}{
+		{time.Date(2020, 1, 2, 3, 4, 5, 123456789, zone), []interface{}{"datetime", "2020-01-02 03:04:05.123457", 28800.0}},
+		{time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), []interface{}{"datetime", "2020-01-02 03:04:05.000000", 0.0}},
+		{90 * time.Minute, []interface{}{"timedelta", "1:30:00"}},
+		{[]byte{0, 255, 'p'}, []interface{}{bytesName, []interface{}{0.0, 255.0, 112.0}}},
+		{price, []interface{}{"Decimal", "12345678901234567890.125"}},
+		{complex(1, -2), []interface{}{"complex", "(1-2j)"}},
+		{huge, []interface{}{pythonIntName(v, huge), "123456789012345678901234567890"}},
+	}
+	for _, c := range cases {
+		var described []interface{}
+		result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "describe", c.param)
+		assert.Equal(t, true, result.NoError, result.Inspect())
+		assert.Nil(t, result.Decode(&described))
+		assert.Equal(t, c.expected, described, result.JsonRepresentation)
+	}
+}
+
+// pythonIntName return type name of python int holding n
+func pythonIntName(version int, n *big.Int) string {
+	if version == pfunc.Python2 && !n.IsInt64() {
+		return "long"
+	}
+	return "int"
+}
+
+func TestCodecPythonValues(t *testing.T) {
+	var values struct {
+		Aware   time.Time     `json:"aware"`
+		Naive   time.Time     `json:"naive"`
+		Date    time.Time     `json:"date"`
+		Delta   time.Duration `json:"delta"`
+		Bytes   []byte        `json:"bytes"`
+		Decimal big.Float     `json:"decimal"`
+		Set     []int         `json:"set"`
+		Tuple   []interface{} `json:"tuple"`
+		Complex complex64     `json:"complex"`
+		Big     *big.Int      `json:"big"`
+	}
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "python_values")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Nil(t, result.Decode(&values))
+
+	assert.True(t, time.Date(2020, 1, 1, 19, 4, 5, 123456000, time.UTC).Equal(values.Aware))
+	_, offset := values.Aware.Zone()
+	assert.Equal(t, 8*3600, offset)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), values.Naive)
+	assert.Equal(t, time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC), values.Date)
+	assert.Equal(t, 24*time.Hour+2*time.Second+3*time.Microsecond, values.Delta)
+	assert.Equal(t, []byte("\x00\xffpfunc"), values.Bytes)
+	assert.Equal(t, "3.141592653589793238462643383279", values.Decimal.Text('f', 30))
+	assert.Equal(t, []int{1, 2, 3}, values.Set)
+	assert.Equal(t, []interface{}{1.0, "two"}, values.Tuple)
+	assert.Equal(t, complex64(complex(1.5, -2)), values.Complex)
+	assert.Equal(t, new(big.Int).Lsh(big.NewInt(1), 100), values.Big)
+
+	// tagged values in interface{} become go values
+	var generic map[string]interface{}
+	assert.Nil(t, result.Decode(&generic))
+	assert.IsType(t, time.Time{}, generic["aware"])
+	assert.Equal(t, 24*time.Hour+2*time.Second+3*time.Microsecond, generic["delta"])
+	assert.Equal(t, []byte("\x00\xffpfunc"), generic["bytes"])
+	assert.IsType(t, &big.Float{}, generic["decimal"])
+	assert.Equal(t, complex(1.5, -2), generic["complex"])
+	assert.Equal(t, new(big.Int).Lsh(big.NewInt(1), 100), generic["big"])
+
+	// decimals can be decoded into floats
+	var f struct {
+		Decimal float64 `json:"decimal"`
+	}
+	assert.Nil(t, result.Decode(&f))
+	assert.InDelta(t, 3.14159265, f.Decimal, 1e-8)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	v, err := pfunc.ProbePythonVersion(pfunc.GetPythonExecutable())
+	assert.Nil(t, err)
+	price, _ := new(big.Float).SetString("0.1")
+	event := Event{
+		Name:     "launch",
+		At:       time.Date(1999, 12, 31, 23, 59, 59, 999999000, time.FixedZone("", -5*3600-1800)),
+		Duration: -1500 * time.Millisecond,
+		Data:     []byte("binary \x00 data"),
+		Price:    price,
+		Phase:    complex(0, 1),
+		Extra:    []interface{}{time.Duration(0), []byte{}, complex64(2)},
+	}
+	extra := []interface{}{time.Duration(0), []byte{}, complex(2, 0)}
+	if v == pfunc.Python2 {
+		// bytes is str in python 2, which returns as text
+		event.Data = nil
+		extra[1] = ""
+	}
+	check := func(echoed Event) {
+		assert.Equal(t, event.Name, echoed.Name)
+		assert.True(t, event.At.Equal(echoed.At), echoed.At.String())
+		assert.Equal(t, event.Duration, echoed.Duration)
+		assert.Equal(t, event.Data, echoed.Data)
+		assert.Equal(t, "0.1", echoed.Price.Text('g', 10))
+		assert.Equal(t, event.Phase, echoed.Phase)
+		assert.Equal(t, extra, echoed.Extra)
+	}
+
+	echoed, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "echo").Return(Event{}).Params(event).Do()
+	assert.Nil(t, err)
+	check(echoed.(Event))
+
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+	echoed, err = pfunc.Func("dirs/a/b/c/pfunc_test.py", "echo").Pool(pool).Return(Event{}).Params(event).Do()
+	assert.Nil(t, err)
+	check(echoed.(Event))
+
+	events, errs := pfunc.Func("dirs/a/b/c/pfunc_test.py", "echo").Return(Event{}).Params([]Event{event, event}).Stream(context.Background())
+	n := 0
+	for e := range events {
+		check(e.(Event))
+		n++
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, 2, n)
+
+	results := pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "echo", [][]interface{}{{event.At}, {event.Duration}})
+	var at time.Time
+	assert.Nil(t, results[0].Decode(&at))
+	assert.True(t, event.At.Equal(at))
+	var d time.Duration
+	assert.Nil(t, results[1].Decode(&d))
+	assert.Equal(t, event.Duration, d)
+}
+
+func TestCodecCallback(t *testing.T) {
+	var received time.Time
+	r := pfunc.NewRunner()
+	assert.Nil(t, r.RegisterCallback("shift", func(at time.Time, d time.Duration) time.Time {
+		received = at
+		return at.Add(d)
+	}))
+	at := time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC)
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "call_callback", "shift", at, time.Hour)
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.True(t, at.Equal(received))
+	var shifted time.Time
+	assert.Nil(t, result.Decode(&shifted))
+	assert.True(t, at.Add(time.Hour).Equal(shifted))
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	bs, err := pfunc.Marshal(map[string]interface{}{"d": time.Second, "b": []byte("x"), "n": 1})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"b":{"__pfunc__":"bytes","value":"eA=="},"d":{"__pfunc__":"timedelta","value":1000000},"n":1}`, string(bs))
+
+	var v map[string]interface{}
+	assert.Nil(t, pfunc.Unmarshal(bs, &v))
+	assert.Equal(t, map[string]interface{}{"d": time.Second, "b": []byte("x"), "n": 1.0}, v)
+
+	var c complex128
+	assert.Nil(t, pfunc.Unmarshal([]byte("2.5"), &c))
+	assert.Equal(t, complex(2.5, 0), c)
+
+	assert.NotNil(t, pfunc.Unmarshal([]byte(`{"__pfunc__": "bytes", "value": "eA=="}`), &c))
+}
+
+func TestUnmarshalLargeInts(t *testing.T) {
+	// ints float64 can not hold exactly become *big.Int in interface{}, others stay float64
+	var v interface{}
+	assert.Nil(t, pfunc.Unmarshal([]byte(`[9007199254740992, 9007199254740993, -18446744073709551617, 0.12345678901234567, "12345678901234567"]`), &v))
+	large, _ := new(big.Int).SetString("-18446744073709551617", 10)
+	assert.Equal(t, []interface{}{9007199254740992.0, big.NewInt(9007199254740993), large, 0.12345678901234567, "12345678901234567"}, v)
+
+	assert.Nil(t, pfunc.Unmarshal([]byte(`{"id": 1234567890123456789}`), &v))
+	assert.Equal(t, map[string]interface{}{"id": big.NewInt(1234567890123456789)}, v)
+
+	var i int64
+	assert.Nil(t, pfunc.Unmarshal([]byte(`1234567890123456789`), &i))
+	assert.Equal(t, int64(1234567890123456789), i)
+
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "echo", `say "hi"`)
+	assert.Equal(t, `say "hi"`, result.MustString())
+}
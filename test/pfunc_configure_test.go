@@ -29,8 +29,10 @@ func Test(t *testing.T) {
 	assert.Equal(t, pfunc.ExceptionStartDefault, pfunc.GetExceptionStart())
 	assert.Equal(t, pfunc.ExceptionEndDefault, pfunc.GetExceptionEnd())
 
+	executable := pfunc.GetPythonExecutable()
 	pfunc.SetPythonExecutable("python27")
 	assert.Equal(t, "python27", pfunc.GetPythonExecutable())
+	pfunc.SetPythonExecutable(executable)
 
 	pfunc.SetInjectVarNamePrefix("my_inject_var_name_prefix_")
 	assert.Equal(t, "my_inject_var_name_prefix_", pfunc.GetInjectVarNamePrefix())
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvokeContextTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := pfunc.CallContext(ctx, "dirs/a/b/c/pfunc_test.py", "sleep", 30)
+	fmt.Println(result.Inspect())
+	assert.True(t, time.Since(start) < 10*time.Second)
+	assert.Equal(t, false, result.NoError)
+	assert.Equal(t, pfunc.ErrTimeout, result.Exception)
+	assert.Contains(t, result.Output, "sleep started")
+}
+
+func TestInvokeContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		cancel()
+	}()
+
+	result := pfunc.InvokeContext(ctx, "dirs/a/b/c/pfunc_test.py", "sleep", []interface{}{30})
+	assert.Equal(t, false, result.NoError)
+	assert.Equal(t, pfunc.ErrCanceled, result.Exception)
+
+	result = pfunc.InvokeContext(ctx, "dirs/a/b/c/pfunc_test.py", "add", []interface{}{1, 2})
+	assert.Equal(t, pfunc.ErrCanceled, result.Exception)
+}
+
+func TestInvokeContextKillChildren(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("process state is read from /proc")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result := pfunc.CallContext(ctx, "dirs/a/b/c/pfunc_test.py", "sleep_in_child", 30)
+	assert.Equal(t, pfunc.ErrTimeout, result.Exception)
+
+	line := pfunc.FindLine(result.Output, "child")
+	pid, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "child")))
+	assert.Nil(t, err)
+
+	// killed child is gone or left as zombie
+	time.Sleep(100 * time.Millisecond)
+	stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err == nil {
+		assert.Contains(t, string(stat), ") Z ")
+	}
+}
+
+func TestWrapFunctionDoContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	i, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "sleep").
+		Params(30).
+		Return(int(-1)).
+		DoContext(ctx)
+	assert.Equal(t, -1, i)
+	assert.Equal(t, pfunc.ErrTimeout, err)
+
+	i, err = pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").
+		Params(6, 3).
+		Return(int(0)).
+		DoContext(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, i)
+
+	// args given to DoContext replace params
+	i, err = pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").
+		Params(6, 3).
+		Return(int(0)).
+		DoContext(context.Background(), 8, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, i)
+}
+
+func TestPoolInvokeContext(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	result := pool.CallContext(ctx, "dirs/a/b/c/pfunc_test.py", "sleep", 30)
+	assert.Equal(t, pfunc.ErrTimeout, result.Exception)
+
+	result = pool.Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 3, result.MustInt())
+}
+
+func TestPoolInvokeContextTimeoutKeepsOutput(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	result := pool.CallContext(ctx, "dirs/a/b/c/pfunc_test.py", "sleep", 30)
+	assert.Equal(t, pfunc.ErrTimeout, result.Exception)
+	assert.Equal(t, "sleep started\n", result.Output)
+
+	// the restarted worker sends output of the next request only
+	result = pool.Call("dirs/a/b/c/pfunc_test.py", "do_print")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Equal(t, "hello world\n", result.Output)
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+type Coordinate struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type Bank struct {
+	Owner   string       `json:"owner"`
+	Balance float64      `json:"balance"`
+	History []Coordinate `json:"history"`
+	Secret  string       `json:"_secret"`
+}
+
+type Purchase struct {
+	ID     int           `json:"id"`
+	Status int           `json:"status"`
+	Lines  []interface{} `json:"lines"`
+	Color  string        `json:"color"`
+}
+
+// skip test on python 2, which has no enum and dataclasses
+func skipPython2(t *testing.T) {
+	v, err := pfunc.ProbePythonVersion(pfunc.GetPythonExecutable())
+	assert.Nil(t, err)
+	if v == pfunc.Python2 {
+		t.Skip("python 2 has no enum and dataclasses")
+	}
+}
+
+func TestEncodeNamedTuple(t *testing.T) {
+	p, err := pfunc.Func("dirs/a/b/c/pfunc_records.py", "point").Return(Coordinate{}).Params(3, 4).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, Coordinate{3, 4}, p)
+
+	var points struct {
+		Path   []Coordinate `json:"path"`
+		Origin []Coordinate `json:"origin"`
+	}
+	result := pfunc.Call("dirs/a/b/c/pfunc_records.py", "points")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Nil(t, result.Decode(&points))
+	assert.Equal(t, []Coordinate{{1, 2}, {3, 4}}, points.Path)
+	assert.Equal(t, []Coordinate{{0, 0}}, points.Origin)
+
+	r := pfunc.DefaultRunner().With(pfunc.WithEncoding(pfunc.Encoding{NamedTupleAsList: true}))
+	result = r.Call("dirs/a/b/c/pfunc_records.py", "point", 3, 4)
+	assert.Equal(t, "[3, 4]", result.JsonRepresentation)
+}
+
+func TestEncodeObjects(t *testing.T) {
+	bank, err := pfunc.Func("dirs/a/b/c/pfunc_records.py", "account").Return(Bank{}).Params("tom", 12).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, Bank{Owner: "tom", Balance: 12, History: []Coordinate{{0, 12}}}, bank)
+
+	// slots, the __pfunc__ hook and nested objects
+	result := pfunc.Call("dirs/a/b/c/pfunc_records.py", "pair")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	var pair map[string]interface{}
+	assert.Nil(t, result.Decode(&pair))
+	assert.Equal(t, map[string]interface{}{
+		"left":  map[string]interface{}{"amount": 1.5, "currency": "USD"},
+		"right": []interface{}{map[string]interface{}{"value": 1.0}},
+	}, pair)
+
+	// objects fail without their attributes, unless an encoder encodes them
+	r := pfunc.DefaultRunner().With(pfunc.WithEncoding(pfunc.Encoding{NoObjects: true}))
+	result = r.Call("dirs/a/b/c/pfunc_records.py", "opaque")
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "is not JSON serializable")
+
+	r = r.With(pfunc.WithEncoding(pfunc.Encoding{NoObjects: true, Encoders: []string{"pfunc_records.encode_opaque"}}))
+	result = r.Call("dirs/a/b/c/pfunc_records.py", "wallet")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	var wallet map[string]interface{}
+	assert.Nil(t, result.Decode(&wallet))
+	assert.Equal(t, map[string]interface{}{
+		"cash":  map[string]interface{}{"amount": 1.5, "currency": "USD"},
+		"other": []interface{}{"opaque 1"},
+	}, wallet)
+	assert.NotContains(t, result.Process.Env, "PFUNC_ENCODING")
+}
+
+func TestEncodingInPoolAndStream(t *testing.T) {
+	r := pfunc.DefaultRunner().With(pfunc.WithEncoding(pfunc.Encoding{NoObjects: true, Encoders: []string{"pfunc_records.encode_opaque"}}))
+	pool, err := r.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+	result := pool.Call("dirs/a/b/c/pfunc_records.py", "opaque")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Equal(t, `"opaque 1"`, result.JsonRepresentation)
+	result = pool.Call("dirs/a/b/c/pfunc_records.py", "account", "tom", 1)
+	assert.Equal(t, false, result.NoError)
+
+	items, errs := pfunc.Func("dirs/a/b/c/pfunc_records.py", "walk").Return(Coordinate{}).Params(3).Stream(context.Background())
+	var walked []Coordinate
+	for item := range items {
+		walked = append(walked, item.(Coordinate))
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []Coordinate{{0, 0}, {1, 1}, {2, 4}}, walked)
+}
+
+func TestEncodeEnumAndDataclass(t *testing.T) {
+	skipPython2(t)
+
+	status, err := pfunc.Func("dirs/a/b/c/pfunc_records.py", "status").Return(0).Params("PAID").Do()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, status)
+
+	order, err := pfunc.Func("dirs/a/b/c/pfunc_records.py", "order").Return(Purchase{}).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, Purchase{
+		ID:     7,
+		Status: 2,
+		Lines:  []interface{}{map[string]interface{}{"x": 1.0, "y": 1.0}, map[string]interface{}{"amount": 0.99, "currency": "USD"}},
+		Color:  "green",
+	}, order)
+
+	r := pfunc.DefaultRunner().With(pfunc.WithEncoding(pfunc.Encoding{EnumNames: true}))
+	result := r.Call("dirs/a/b/c/pfunc_records.py", "orders")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Equal(t, `["NEW", "PAID", "RED"]`, result.JsonRepresentation)
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPythonError(t *testing.T) {
+	_, err := divide(1, 0)
+	fmt.Println(err)
+
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "ZeroDivisionError", pe.Type)
+	assert.Equal(t, "ZeroDivisionError", pe.QualifiedType())
+	assert.Contains(t, pe.Message, "by zero")
+	assert.Equal(t, 1, len(pe.Args))
+	assert.Contains(t, err.Error(), "Traceback (most recent call last):")
+
+	last := pe.Traceback[len(pe.Traceback)-1]
+	assert.Contains(t, last.File, "pfunc_test.py")
+	assert.Equal(t, "divide", last.Function)
+	assert.Equal(t, "return a // b", last.Source)
+	assert.True(t, last.Line > 0)
+}
+
+func TestPythonErrorCustomType(t *testing.T) {
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "raise_custom", 42, "bad input")
+	assert.Equal(t, false, result.NoError)
+
+	pe, ok := result.Exception.(*pfunc.PythonError)
+	assert.True(t, ok)
+	assert.Equal(t, "PfuncTestError", pe.Type)
+	assert.Equal(t, "pfunc_test", pe.Module)
+	assert.Equal(t, "pfunc_test.PfuncTestError", pe.QualifiedType())
+	assert.Equal(t, []interface{}{float64(42), "bad input"}, pe.Args)
+}
+
+func TestPythonErrorChain(t *testing.T) {
+	v, err := pfunc.ProbePythonVersion(pfunc.GetPythonExecutable())
+	assert.Nil(t, err)
+	if v < pfunc.Python3 {
+		t.Skip("exception chaining needs python 3")
+	}
+
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "raise_with_context")
+	pe := result.Exception.(*pfunc.PythonError)
+	assert.Equal(t, "ValueError", pe.Type)
+	assert.Nil(t, pe.Cause)
+	assert.Equal(t, "KeyError", pe.Context.Type)
+
+	var inner *pfunc.PythonError
+	assert.True(t, errors.As(errors.Unwrap(pe), &inner))
+	assert.Equal(t, "KeyError", inner.Type)
+
+	result = pfunc.Call("dirs/a/b/c/pfunc_test.py", "raise_with_cause")
+	pe = result.Exception.(*pfunc.PythonError)
+	assert.Equal(t, "RuntimeError", pe.Type)
+	assert.Equal(t, "KeyError", pe.Cause.Type)
+	assert.Equal(t, "raise_with_cause", pe.Cause.Traceback[len(pe.Cause.Traceback)-1].Function)
+}
+
+func TestPythonErrorInPool(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	result := pool.Call("dirs/a/b/c/pfunc_test.py", "raise_custom", 1, "pool")
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "pfunc_test.PfuncTestError", pe.QualifiedType())
+	assert.Equal(t, "raise_custom", pe.Traceback[len(pe.Traceback)-1].Function)
+
+	result = pool.Call("dirs/a/b/c/pfunc_test.py", "not_exists")
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "AttributeError", pe.Type)
+}
+
+func TestPythonErrorImport(t *testing.T) {
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "not_exists")
+	fmt.Println(result.Inspect())
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "ImportError", pe.Type)
+}
+
+func TestParseTraceback(t *testing.T) {
+	text := `Traceback (most recent call last):
+  File "<stdin>", line 1, in <module>
+  File "/tmp/m.py", line 4, in f
+    return 1 / 0
+mypkg.errors.BadThing: something: happened
+`
+	pe := pfunc.ParseTraceback(text)
+	assert.NotNil(t, pe)
+	assert.Equal(t, "BadThing", pe.Type)
+	assert.Equal(t, "mypkg.errors", pe.Module)
+	assert.Equal(t, "something: happened", pe.Message)
+	assert.Equal(t, []pfunc.Frame{
+		{File: "<stdin>", Line: 1, Function: "<module>"},
+		{File: "/tmp/m.py", Line: 4, Function: "f", Source: "return 1 / 0"},
+	}, pe.Traceback)
+	assert.Equal(t, text, pe.Error())
+
+	assert.Nil(t, pfunc.ParseTraceback("some warning"))
+
+	// python 3.11 marks the failed expression below the source line
+	text = `Traceback (most recent call last):
+  File "<stdin>", line 457, in <module>
+  File "/tmp/m.py", line 15, in divide
+    return a // b
+           ~~^^~~
+  File "/tmp/n.py", line 3, in g
+    return h(x)[0]
+           ^^^^^^^
+ZeroDivisionError: integer division or modulo by zero
+`
+	pe = pfunc.ParseTraceback(text)
+	assert.NotNil(t, pe)
+	assert.Equal(t, "ZeroDivisionError", pe.Type)
+	assert.Equal(t, []pfunc.Frame{
+		{File: "<stdin>", Line: 457, Function: "<module>"},
+		{File: "/tmp/m.py", Line: 15, Function: "divide", Source: "return a // b"},
+		{File: "/tmp/n.py", Line: 3, Function: "g", Source: "return h(x)[0]"},
+	}, pe.Traceback)
+}
@@ -0,0 +1,97 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEval(t *testing.T) {
+	result := pfunc.Eval("a + b", map[string]interface{}{"a": 1, "b": 2})
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 3, result.MustInt())
+
+	result = pfunc.Eval("sorted(p['name'] for p in people)", map[string]interface{}{
+		"people": []Person{{Name: "Tom"}, {Name: "Ann"}},
+	})
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, `["Ann", "Tom"]`, result.JsonRepresentation)
+
+	result = pfunc.Eval("'unicode ' + s", map[string]interface{}{"s": "世界"})
+	assert.Equal(t, "unicode 世界", result.MustString())
+
+	result = pfunc.Eval("1 / 0", nil)
+	assert.Equal(t, false, result.NoError)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "ZeroDivisionError", pe.Type)
+
+	result = pfunc.Eval("undefined_name", nil)
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "NameError", pe.Type)
+
+	result = pfunc.Eval("a = 1", nil)
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "SyntaxError", pe.Type)
+}
+
+func TestExec(t *testing.T) {
+	code := `
+import math
+total = 0
+for v in values:
+    total += v
+root = math.sqrt(total)
+print("total is {0}".format(total))
+`
+	result := pfunc.Exec(code, map[string]interface{}{"values": []int{7, 9}}, "total", "root")
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, result.Output, "total is 16")
+
+	var globals struct {
+		Total int     `json:"total"`
+		Root  float64 `json:"root"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(result.JsonRepresentation), &globals))
+	assert.Equal(t, 16, globals.Total)
+	assert.Equal(t, 4.0, globals.Root)
+
+	result = pfunc.Exec("x = 1", nil)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "{}", result.JsonRepresentation)
+
+	result = pfunc.Exec("x = 1", nil, "y")
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "NameError", pe.Type)
+
+	result = pfunc.Exec("raise ValueError('bad value')", nil)
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "ValueError", pe.Type)
+	assert.Equal(t, "bad value", pe.Message)
+}
+
+func TestEvalWithRunner(t *testing.T) {
+	r := pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()))
+	p, err := r.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	pooled := r.With(pfunc.WithPool(p))
+	result := pooled.Eval("x * 2", map[string]interface{}{"x": 21})
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 42, result.MustInt())
+
+	result = pooled.Exec("y = x + 1", map[string]interface{}{"x": 1}, "y")
+	assert.Equal(t, `{"y": 2}`, result.JsonRepresentation)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result = r.ExecContext(ctx, "import time\ntime.sleep(5)", nil)
+	assert.Equal(t, pfunc.ErrTimeout, result.Exception)
+}
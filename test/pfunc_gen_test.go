@@ -0,0 +1,171 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:generate go run ../cmd/pfuncgen -script dirs/a/b/c/pfunc_typed.py -package test -o pfunc_typed_gen_test.go -python python3
+
+// the typed script uses annotations, TypedDict and dataclass which need python 3.8 or above
+func skipWithoutTypedPython(t *testing.T) {
+	v, err := pfunc.ProbePythonVersion(pfunc.GetPythonExecutable())
+	if err != nil || v != pfunc.Python3 {
+		t.Skip("typed script needs python 3")
+	}
+}
+
+func TestGeneratedFunctions(t *testing.T) {
+	skipWithoutTypedPython(t)
+	ctx := context.Background()
+
+	d, err := Distance(ctx, Point{X: 0, Y: 0}, Point{X: 3, Y: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, 5.0, d)
+
+	p, err := Middle(ctx, Point{X: 0, Y: 0}, Point{X: 3, Y: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, Point{X: 1.5, Y: 2}, p)
+
+	s, err := Greet(ctx, "Bob", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello, Bob!", s)
+
+	greeting := "Hi"
+	s, err = Greet(ctx, "Bob", &GreetOptions{Greeting: &greeting})
+	assert.Nil(t, err)
+	assert.Equal(t, "Hi, Bob!", s)
+
+	i, err := Total(ctx, 1, 2, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, i)
+
+	ls, err := Labels(ctx, "k_", "=", map[string]interface{}{"b": "2", "a": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"k_a=1", "k_b=2"}, ls)
+
+	found, err := Find(ctx, map[string]int{"a": 1}, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, *found)
+
+	found, err = Find(ctx, map[string]int{"a": 1}, "b")
+	assert.Nil(t, err)
+	assert.Nil(t, found)
+
+	bs, err := Bounds(ctx, []float64{3, 1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{1, 3}, bs)
+
+	u, err := Untyped(ctx, 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 3.0, u)
+
+	assert.Nil(t, Nothing(ctx, "any"))
+}
+
+func TestGeneratedFunctionError(t *testing.T) {
+	skipWithoutTypedPython(t)
+
+	_, err := Untyped(context.Background(), "a", nil)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "TypeError", pe.Type)
+}
+
+func TestGeneratedPositionalOnlyDefaults(t *testing.T) {
+	skipWithoutTypedPython(t)
+	ctx := context.Background()
+
+	i, err := Clamp(ctx, 20, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, i)
+
+	low, high := 5, 30
+	i, err = Clamp(ctx, 1, &ClampOptions{Low: &low})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, i)
+
+	i, err = Clamp(ctx, 20, &ClampOptions{Low: &low, High: &high})
+	assert.Nil(t, err)
+	assert.Equal(t, 20, i)
+
+	// high can not be passed positionally without low
+	_, err = Clamp(ctx, 20, &ClampOptions{High: &high})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ClampOptions.Low")
+}
+
+func TestGeneratedNonASCIIName(t *testing.T) {
+	skipWithoutTypedPython(t)
+
+	i, err := Größe(context.Background(), 3, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, 12, i)
+}
+
+func TestGeneratedDataclassReturn(t *testing.T) {
+	skipWithoutTypedPython(t)
+
+	e, err := Hire(context.Background(), "Ann", 30)
+	assert.Nil(t, err)
+	active, level := true, 5
+	assert.Equal(t, Employee{Name: "Ann", Age: 30, Skills: []string{}, Active: &active, Level: &level}, e)
+
+	// zero values of fields with defaults are sent, nil fields take the defaults
+	active, level = false, 0
+	e, err = Promote(context.Background(), Employee{Name: "Ann", Age: 30, Active: &active, Level: &level})
+	assert.Nil(t, err)
+	assert.Equal(t, false, *e.Active)
+	assert.Equal(t, 1, *e.Level)
+
+	e, err = Promote(context.Background(), Employee{Name: "Ann", Age: 30})
+	assert.Nil(t, err)
+	assert.Equal(t, true, *e.Active)
+	assert.Equal(t, 6, *e.Level)
+}
+
+func TestGeneratedFunctionsWithRunner(t *testing.T) {
+	skipWithoutTypedPython(t)
+
+	dir, err := filepath.Abs(".")
+	assert.Nil(t, err)
+	PfuncTypedRunner = pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()), pfunc.WithWorkDir(os.TempDir()))
+	PfuncTypedScript = filepath.Join(dir, "dirs/a/b/c/pfunc_typed.py")
+	defer func() {
+		PfuncTypedRunner = nil
+		PfuncTypedScript = "dirs/a/b/c/pfunc_typed.py"
+	}()
+
+	i, err := Total(context.Background(), 4, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 9, i)
+}
+
+// generated file must be regenerated after the typed script or pfuncgen changes
+func TestGeneratedFileUpToDate(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not found")
+	}
+	dir, err := ioutil.TempDir("", "pfuncgen")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "gen.go")
+	cmd := exec.Command("go", "run", "../cmd/pfuncgen", "-script", "dirs/a/b/c/pfunc_typed.py", "-package", "test", "-o", output, "-python", "python3")
+	out, err := cmd.CombinedOutput()
+	assert.Nil(t, err, string(out))
+
+	generated, err := ioutil.ReadFile(output)
+	assert.Nil(t, err)
+	committed, err := ioutil.ReadFile("pfunc_typed_gen_test.go")
+	assert.Nil(t, err)
+	assert.True(t, bytes.Equal(generated, committed), "pfunc_typed_gen_test.go is stale, run go generate")
+}
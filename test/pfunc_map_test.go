@@ -0,0 +1,105 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	divide := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Return(0)
+	inputs := [][]interface{}{{6, 3}, {9, 3}, {8, 2}, {10, 5}, {7, 7}}
+	results, err := pfunc.Map(context.Background(), divide, inputs, pfunc.MapConcurrency(3))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{2, 3, 4, 2, 1}, results)
+
+	sqrt := pfunc.Module("math").Func("sqrt").Return(0.0)
+	results, err = pfunc.Map(context.Background(), sqrt, []int{1, 4, 9})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{1.0, 2.0, 3.0}, results)
+
+	results, err = pfunc.Map(context.Background(), sqrt, []int{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	_, err = pfunc.Map(context.Background(), sqrt, 4)
+	assert.NotNil(t, err)
+}
+
+func TestMapStopOnFirstError(t *testing.T) {
+	divide := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Return(0)
+	inputs := [][]interface{}{{6, 3}, {1, 0}, {9, 3}, {8, 2}}
+	results, err := pfunc.Map(context.Background(), divide, inputs, pfunc.MapConcurrency(1))
+
+	var me *pfunc.MapError
+	assert.True(t, errors.As(err, &me))
+	assert.Equal(t, 1, me.Index)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "ZeroDivisionError", pe.Type)
+
+	assert.Equal(t, []interface{}{2, nil, nil, nil}, results)
+}
+
+func TestMapStopKillsRunningInvocations(t *testing.T) {
+	sleep := pfunc.Func("dirs/a/b/c/pfunc_test.py", "sleep").Return(0)
+	start := time.Now()
+	results, err := pfunc.Map(context.Background(), sleep, []interface{}{10, "not a number", 10, 10}, pfunc.MapConcurrency(3))
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	// killed, failed and never started inputs are all nil
+	assert.Equal(t, []interface{}{nil, nil, nil, nil}, results)
+
+	var me *pfunc.MapError
+	assert.True(t, errors.As(err, &me))
+	assert.Equal(t, 1, me.Index)
+}
+
+func TestMapCollectErrors(t *testing.T) {
+	divide := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Return(0)
+	inputs := [][]interface{}{{1, 0}, {6, 3}, {2, 0}, {8, 2}}
+	results, err := pfunc.Map(context.Background(), divide, inputs, pfunc.MapConcurrency(2), pfunc.MapCollectErrors())
+	assert.Equal(t, []interface{}{0, 2, 0, 4}, results)
+
+	var errs pfunc.MapErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, 0, errs[0].Index)
+	assert.Equal(t, 2, errs[1].Index)
+	assert.Contains(t, err.Error(), "ZeroDivisionError")
+}
+
+func TestMapContextCanceled(t *testing.T) {
+	sleep := pfunc.Func("dirs/a/b/c/pfunc_test.py", "sleep").Return(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	results, err := pfunc.Map(ctx, sleep, []int{10, 10, 10, 10}, pfunc.MapConcurrency(2), pfunc.MapCollectErrors())
+	assert.Equal(t, pfunc.ErrTimeout, err)
+	assert.Equal(t, []interface{}{nil, nil, nil, nil}, results)
+}
+
+func TestMapSharedWrapInfo(t *testing.T) {
+	divide := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Return(0).ParamDefaults(0, 2)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := pfunc.Map(context.Background(), divide, []int{2, 4, 6}, pfunc.MapConcurrency(2))
+			assert.Nil(t, err)
+			assert.Equal(t, []interface{}{1, 2, 3}, results)
+		}()
+	}
+	wg.Wait()
+
+	result, err := divide.Params(8).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result)
+	result, err = divide.Params(8, 4).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result)
+}
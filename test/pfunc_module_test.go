@@ -0,0 +1,69 @@
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModule(t *testing.T) {
+	result := pfunc.Module("os.path").Call("join", "a", "b")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, filepath.Join("a", "b"), result.MustString())
+
+	s, err := pfunc.Module("json").Func("dumps").Return("").Params([]int{1, 2}).KeyWrodParam("separators", []string{",", ":"}).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, "[1,2]", s)
+
+	result = pfunc.Module("math").Call("sqrt", 16)
+	assert.Equal(t, float32(4), result.MustFloat())
+
+	result = pfunc.Module("module_not_exists").Call("f")
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "module_not_exists")
+
+	result = pfunc.Module("os path").Call("join", "a")
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "invalid python module name")
+}
+
+func TestModuleInPythonPath(t *testing.T) {
+	dir, err := filepath.Abs("dirs/pkgs")
+	assert.Nil(t, err)
+	r := pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()), pfunc.WithPythonPaths(dir))
+	metrics := r.Module("mypkg.metrics")
+
+	result := metrics.Call("score", []int{1, 2, 3})
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 6, result.MustInt())
+
+	i, err := metrics.Func("score").Return(0).Params([]int{1, 2}).KeyWrodParam("weight", 3).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, 9, i)
+
+	result = metrics.Class("Scorer").New(2).Call("score", []int{1, 2})
+	assert.Equal(t, 6, result.MustInt())
+
+	items, errs := pfunc.Module("itertools").Func("repeat").Return("").Params("x", 2).Stream(context.Background())
+	var got []string
+	for item := range items {
+		got = append(got, item.(string))
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []string{"x", "x"}, got)
+
+	p, err := r.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+	result = metrics.Pool(p).Call("score", []int{4, 5})
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 9, result.MustInt())
+
+	o, err := metrics.Class("Scorer").New(10).Object(context.Background())
+	assert.Nil(t, err)
+	defer o.Release()
+	assert.Equal(t, 30, o.Call("score", []int{1, 2}).MustInt())
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPyObject(t *testing.T) {
+	p, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	model, err := p.Object(context.Background(), "dirs/a/b/c/pfunc_test.py", "load_model", "m", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, "pfunc_test.Model", model.Type())
+
+	result := model.Call("predict", 2)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 6, result.MustInt())
+
+	result = model.GetAttr("name")
+	assert.Equal(t, "m", result.MustString())
+	assert.Equal(t, 1, model.GetAttr("predictions").MustInt())
+
+	result = model.CallKwargs(context.Background(), "predict", nil, map[string]interface{}{"x": 5})
+	assert.Equal(t, 15, result.MustInt())
+
+	result = model.Call("fail")
+	assert.Equal(t, false, result.NoError)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "ValueError", pe.Type)
+
+	// the model object is returned as its attributes
+	result = model.Call("itself")
+	assert.Equal(t, true, result.NoError)
+	var attributes map[string]interface{}
+	assert.Nil(t, result.Decode(&attributes))
+	assert.Equal(t, map[string]interface{}{"name": "m", "weight": 3.0, "predictions": 2.0}, attributes)
+
+	assert.Nil(t, model.Release())
+	assert.Equal(t, pfunc.ErrObjectReleased, model.Release())
+	assert.Equal(t, pfunc.ErrObjectReleased, model.Call("predict", 1).Exception)
+}
+
+func TestPyObjectFromClass(t *testing.T) {
+	p, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	model, err := p.Object(context.Background(), "dirs/a/b/c/pfunc_test.py", "Model", "m")
+	assert.Nil(t, err)
+	defer model.Release()
+
+	scaled, err := model.CallObject(context.Background(), "scale", 4)
+	assert.Nil(t, err)
+	defer scaled.Release()
+	assert.NotEqual(t, model.ID(), scaled.ID())
+	assert.Equal(t, 8, scaled.Call("predict", 2).MustInt())
+	assert.Equal(t, "m_scaled", scaled.GetAttr("name").MustString())
+
+	// nested classes are qualified by the outer class, python 2 has no qualified names
+	stats, err := model.CallObject(context.Background(), "stats")
+	assert.Nil(t, err)
+	assert.Contains(t, []string{"pfunc_test.Model.Stats", "pfunc_test.Stats"}, stats.Type())
+	assert.Nil(t, stats.Release())
+
+	result := model.Call("fail_nested")
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Contains(t, []string{"pfunc_test.Model.Error", "pfunc_test.Error"}, pe.QualifiedType())
+
+	name, err := model.GetAttrObject(context.Background(), "name")
+	assert.Nil(t, err)
+	assert.Contains(t, []string{"str", "unicode"}, name.Type())
+	assert.Equal(t, "M", name.Call("upper").MustString())
+	assert.Nil(t, name.Release())
+
+	_, err = p.Object(context.Background(), "dirs/a/b/c/pfunc_test.py", "load_model", "missing weight")
+	assert.NotNil(t, err)
+
+	// canceling a getattr kills the worker keeping the objects
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Equal(t, 4, scaled.GetAttrContext(ctx, "weight").MustInt())
+	cancel()
+	assert.Equal(t, pfunc.ErrCanceled, scaled.GetAttrContext(ctx, "weight").Exception)
+	assert.Equal(t, pfunc.ErrObjectInvalid, scaled.GetAttr("weight").Exception)
+}
+
+func TestPyObjectReferenceCount(t *testing.T) {
+	p, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	model, err := p.Object(context.Background(), "dirs/a/b/c/pfunc_test.py", "load_model", "m", 1)
+	assert.Nil(t, err)
+
+	// another handle of the same python object holds its own reference
+	same, err := model.CallObject(context.Background(), "itself")
+	assert.Nil(t, err)
+	assert.Equal(t, model.ID(), same.ID())
+
+	model.Retain()
+	assert.Nil(t, model.Release())
+	assert.Nil(t, model.Release())
+	assert.Equal(t, pfunc.ErrObjectReleased, model.Call("predict", 1).Exception)
+
+	assert.Equal(t, 1, same.Call("predict", 1).MustInt())
+	assert.Nil(t, same.Release())
+
+	// the python object is dropped with its last reference
+	result := p.Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	assert.Equal(t, 3, result.MustInt())
+}
+
+func TestPyObjectInvalidAfterWorkerExited(t *testing.T) {
+	p, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+
+	model, err := p.Object(context.Background(), "dirs/a/b/c/pfunc_test.py", "load_model", "m", 1)
+	assert.Nil(t, err)
+
+	result := p.Call("dirs/a/b/c/pfunc_test.py", "crash", 3)
+	assert.Equal(t, false, result.NoError)
+
+	assert.Equal(t, pfunc.ErrObjectInvalid, model.Call("predict", 1).Exception)
+	_, err = model.CallObject(context.Background(), "scale")
+	assert.Equal(t, pfunc.ErrObjectInvalid, err)
+	assert.Nil(t, model.Release())
+
+	// restarted worker serves new objects
+	model, err = p.Object(context.Background(), "dirs/a/b/c/pfunc_test.py", "load_model", "m", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, model.Call("predict", 2).MustInt())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, pfunc.ErrTimeout, p.CallContext(ctx, "dirs/a/b/c/pfunc_test.py", "sleep", 5).Exception)
+	assert.Equal(t, pfunc.ErrObjectInvalid, model.Call("predict", 2).Exception)
+
+	model, err = p.Object(context.Background(), "dirs/a/b/c/pfunc_test.py", "load_model", "m", 2)
+	assert.Nil(t, err)
+	p.Close()
+	assert.Equal(t, pfunc.ErrObjectInvalid, model.Call("predict", 2).Exception)
+}
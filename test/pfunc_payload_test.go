@@ -0,0 +1,254 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+// values which encoding/json can marshal, and should arrive in python with the same value
+var roundTripValues = []interface{}{
+	nil,
+	true,
+	false,
+	0,
+	-42,
+	int64(1) << 53,
+	3.25,
+	-1e-10,
+	"",
+	"plain",
+	"quote ' double quote \" backslash \\ slash /",
+	"line\nbreak\r\ttab",
+	"''' triple quotes \"\"\"",
+	"中文 日本語 한국어",
+	"emoji 😀 and 𝄞",
+	"line separator   paragraph separator  ",
+	"nul \x00 and bell \x07",
+	"html <script>&amp;</script>",
+	[]interface{}{},
+	[]interface{}{1, "two", 3.5, nil, true},
+	map[string]interface{}{},
+	map[string]interface{}{
+		"nested": map[string]interface{}{
+			"list": []interface{}{map[string]interface{}{"deep": []interface{}{false, nil}}},
+			"中文":   "值",
+		},
+		"null":  nil,
+		"true":  true,
+		"false": false,
+	},
+	Person{Name: "Tom 'the' \"cat\"", Age: 3, Hobby: []string{"mice", " "}},
+	strings.Repeat("big payload ", 10000),
+}
+
+// normalize value by a json round trip, as python returns it back through json
+func normalize(t *testing.T, v interface{}) interface{} {
+	bs, err := json.Marshal(v)
+	assert.Nil(t, err)
+	var n interface{}
+	assert.Nil(t, json.Unmarshal(bs, &n))
+	return n
+}
+
+func TestParamsRoundTrip(t *testing.T) {
+	for _, v := range roundTripValues {
+		result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "echo", v)
+		if !assert.Equal(t, true, result.NoError, fmt.Sprintf("%v", v)) {
+			fmt.Println(result.Inspect())
+			continue
+		}
+		var r interface{}
+		assert.Nil(t, json.Unmarshal([]byte(result.JsonRepresentation), &r))
+		assert.Equal(t, normalize(t, v), r)
+	}
+}
+
+func TestParamsRoundTripInPool(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	for _, v := range roundTripValues {
+		result := pool.Call("dirs/a/b/c/pfunc_test.py", "echo", v)
+		assert.Equal(t, true, result.NoError, fmt.Sprintf("%v", v))
+		var r interface{}
+		assert.Nil(t, json.Unmarshal([]byte(result.JsonRepresentation), &r))
+		assert.Equal(t, normalize(t, v), r)
+	}
+}
+
+func TestParamsPythonTypes(t *testing.T) {
+	typeNames := map[string]interface{}{
+		"bool":     true,
+		"NoneType": nil,
+		"int":      7,
+		"float":    7.5,
+		"list":     []int{1},
+		"dict":     map[string]int{"a": 1},
+	}
+	for name, v := range typeNames {
+		i, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "type_name").
+			Params(v).
+			Return("").
+			Do()
+		assert.Nil(t, err)
+		assert.Equal(t, name, i)
+	}
+}
+
+func TestKeywordParamsRoundTrip(t *testing.T) {
+	r, err := FirstParamAndOtherParams(nil, "Ming ", 33, []string{"😀"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"first": nil,
+		"name":  "Ming ",
+		"age":   float64(33),
+		"hobby": []interface{}{"😀"},
+	}, r)
+}
+
+func TestParamsNotSerializable(t *testing.T) {
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "echo", math.NaN())
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "can not serialize params to json value")
+}
+
+func TestPythonStringLiteral(t *testing.T) {
+	assert.Equal(t, `u'abc'`, pfunc.PythonStringLiteral("abc"))
+	assert.Equal(t, `u'\'\\\n\x00'`, pfunc.PythonStringLiteral("'\\\n\x00"))
+	assert.Equal(t, `u'\u4e2d\U0001f600'`, pfunc.PythonStringLiteral("中😀"))
+}
+
+// count temp files of payloads left in temp directory
+func payloadTempFiles(t *testing.T) int {
+	matches, err := filepath.Glob(filepath.Join(os.TempDir(), "pfunc_payload_*"))
+	assert.Nil(t, err)
+	return len(matches)
+}
+
+func TestLargeParams(t *testing.T) {
+	left := payloadTempFiles(t)
+	r := pfunc.DefaultRunner().With(pfunc.WithPayloadThreshold(1024))
+	values := make([]int, 100000)
+	for i := range values {
+		values[i] = i
+	}
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "sum_and_size", values)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "[4999950000, 100000]", result.JsonRepresentation)
+	assert.Contains(t, result.TempScript, "bytes of json params in a temp file")
+	assert.NotContains(t, result.TempScript, "99999")
+	// params alone are more than 500 KiB
+	assert.Less(t, len(result.TempScript), len(pfunc.PythonRuntime)+10000)
+	assert.Less(t, len(result.Inspect()), len(pfunc.PythonRuntime)+10000)
+	assert.Equal(t, left, payloadTempFiles(t))
+
+	result = r.With(pfunc.WithPayloadThreshold(0)).Call("dirs/a/b/c/pfunc_test.py", "sum_and_size", values)
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, result.TempScript, "99999")
+}
+
+func TestLargeParamsRoundTrip(t *testing.T) {
+	r := pfunc.DefaultRunner().With(pfunc.WithPayloadThreshold(1))
+	for _, v := range roundTripValues {
+		result := r.Call("dirs/a/b/c/pfunc_test.py", "echo", v)
+		if !assert.Equal(t, true, result.NoError, fmt.Sprintf("%v", v)) {
+			fmt.Println(result.Inspect())
+			continue
+		}
+		assert.Contains(t, result.TempScript, "pfunc_load_payload(")
+		var value interface{}
+		assert.Nil(t, json.Unmarshal([]byte(result.JsonRepresentation), &value))
+		assert.Equal(t, normalize(t, v), value)
+	}
+}
+
+func TestLargeResult(t *testing.T) {
+	left := payloadTempFiles(t)
+	n := 300000
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "make_list", n)
+	assert.Equal(t, true, result.NoError)
+	assert.Greater(t, len(result.JsonRepresentation), pfunc.PayloadThresholdDefault)
+	var values []int
+	assert.Nil(t, json.Unmarshal([]byte(result.JsonRepresentation), &values))
+	assert.Equal(t, n, len(values))
+	assert.Equal(t, n-1, values[n-1])
+	assert.Contains(t, result.Inspect(), "bytes of json>")
+	assert.Equal(t, left, payloadTempFiles(t))
+
+	values = nil
+	list, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "make_list").Return(values).With(pfunc.WithPayloadThreshold(100)).Params(1000).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, len(list.([]int)))
+}
+
+func TestLargePayloadBatchAndStream(t *testing.T) {
+	r := pfunc.DefaultRunner().With(pfunc.WithPayloadThreshold(64))
+	results := r.InvokeBatch("dirs/a/b/c/pfunc_test.py", "squares", [][]interface{}{{100}, {3}, {200}})
+	assert.Equal(t, true, results[1].NoError)
+	assert.Equal(t, "[0, 1, 4]", results[1].JsonRepresentation)
+	assert.Equal(t, true, results[2].NoError)
+	assert.Contains(t, results[0].TempScript, "bytes of json params in a temp file")
+
+	items, errs := r.InvokeStream(context.Background(), "dirs/a/b/c/pfunc_test.py", "sum_and_size", []interface{}{make([]int, 1000)})
+	var got []string
+	for item := range items {
+		got = append(got, string(item))
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []string{"0", "1000"}, got)
+}
+
+func TestLargePoolRequest(t *testing.T) {
+	left := payloadTempFiles(t)
+	pool, err := pfunc.DefaultRunner().With(pfunc.WithPayloadThreshold(1024)).NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	values := make([]int, 100000)
+	for i := range values {
+		values[i] = i
+	}
+	result := pool.Call("dirs/a/b/c/pfunc_test.py", "sum_and_size", values)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "[4999950000, 100000]", result.JsonRepresentation)
+	assert.Contains(t, result.TempScript, "bytes of json request in a temp file")
+	assert.NotContains(t, result.TempScript, "99999")
+	assert.Less(t, len(result.Inspect()), 20000)
+	assert.Equal(t, left, payloadTempFiles(t))
+
+	result = pool.Call("dirs/a/b/c/pfunc_test.py", "sum_and_size", []int{1, 2})
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, result.TempScript, `"args":[[1,2]]`)
+}
+
+func TestLargePoolResponse(t *testing.T) {
+	left := payloadTempFiles(t)
+	pool, err := pfunc.DefaultRunner().With(pfunc.WithPayloadThreshold(1024)).NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	n := 300000
+	result := pool.Call("dirs/a/b/c/pfunc_test.py", "make_list", n)
+	assert.Equal(t, true, result.NoError)
+	var values []int
+	assert.Nil(t, json.Unmarshal([]byte(result.JsonRepresentation), &values))
+	assert.Equal(t, n, len(values))
+	assert.Equal(t, n-1, values[n-1])
+	// response files are removed once read
+	assert.Equal(t, left, payloadTempFiles(t))
+
+	results := pfunc.DefaultRunner().With(pfunc.WithPool(pool)).InvokeBatch("dirs/a/b/c/pfunc_test.py", "make_list", [][]interface{}{{1000}, {2}})
+	assert.Equal(t, true, results[0].NoError)
+	assert.Equal(t, "[0, 1]", results[1].JsonRepresentation)
+	assert.Equal(t, left, payloadTempFiles(t))
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolInvoke(t *testing.T) {
+	pool, err := pfunc.NewPool(2)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	result := pool.Invoke("dirs/a/b/c/pfunc_test.py", "add", []interface{}{1, 2})
+	fmt.Println(result.Inspect())
+	assert.Equal(t, true, result.NoError)
+	assert.Empty(t, result.Exception)
+	assert.Equal(t, 3, result.MustInt())
+
+	result = pool.Call("dirs/a/b/c/pfunc_test.py", "do_print")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "null", result.JsonRepresentation)
+	assert.Equal(t, "hello world\n", result.Output)
+
+	result = pool.Call("dirs/a/b/c/pfunc_test.py", "divide", 2, 0)
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "ZeroDivisionError")
+}
+
+func TestPoolKeepsModuleState(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	first := pool.Call("dirs/a/b/c/pfunc_test.py", "count_calls").MustInt()
+	second := pool.Call("dirs/a/b/c/pfunc_test.py", "count_calls").MustInt()
+	assert.Equal(t, first+1, second)
+}
+
+func TestPoolRestartCrashedWorker(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	result := pool.Call("dirs/a/b/c/pfunc_test.py", "crash", 3)
+	fmt.Println(result.Inspect())
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "python worker exited")
+
+	result = pool.Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 3, result.MustInt())
+}
+
+func TestPoolConcurrentInvoke(t *testing.T) {
+	pool, err := pfunc.NewPool(3)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	wg := sync.WaitGroup{}
+	results := make([]int, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = pool.Call("dirs/a/b/c/pfunc_test.py", "add", i, i).MustInt()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, r := range results {
+		assert.Equal(t, i*2, r)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	pool, err := pfunc.NewPool(2)
+	assert.Nil(t, err)
+	assert.Nil(t, pool.Close())
+	assert.Nil(t, pool.Close())
+
+	result := pool.Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), pfunc.ErrPoolClosed.Error())
+}
+
+func TestWrapFunctionInPool(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	i, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").
+		Params(6, 3).
+		Return(int(0)).
+		Pool(pool).
+		Do()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, i)
+
+	pfunc.SetPool(pool)
+	defer pfunc.SetPool(nil)
+
+	r, err := FirstParamAndOtherParams("Lee", "Ming", 33, []string{"Video Game", "Programing"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Ming", r["name"])
+
+	result := pfunc.Invoke("dirs/a/b/c/pfunc_test.py", "add", []interface{}{1, 2})
+	assert.Equal(t, 3, result.MustInt())
+}
+
+func TestPoolLoadsScriptsByFile(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	// scripts of the same name in different directories do not collide
+	assert.Equal(t, "one", pool.Call("dirs/same/one/util.py", "who").MustString())
+	assert.Equal(t, "two", pool.Call("dirs/same/two/util.py", "who").MustString())
+	assert.Equal(t, "one", pool.Call("dirs/same/one/util.py", "who").MustString())
+
+	// a script named like a standard module is not shadowed by it
+	result := pool.Call("dirs/same/shadow/types.py", "who")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Equal(t, "shadow", result.MustString())
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepared(t *testing.T) {
+	w := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Return(0).ParamDefaults(0, 2)
+	divide := w.Prepare()
+
+	result, err := divide.Call(6, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result)
+
+	result, err = divide.Call(8)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result)
+
+	result, err = divide.Call(1, 0)
+	assert.Equal(t, 0, result)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "ZeroDivisionError", pe.Type)
+
+	w.Return("").ParamDefaults(0, 4)
+	result, err = divide.Call(8)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, result)
+}
+
+func TestPreparedKeywords(t *testing.T) {
+	w := pfunc.Func("dirs/a/b/c/pfunc_test.py", "first_param_and_other_params").Return(map[string]interface{}{}).
+		KeyWrodParam("name", "Tom")
+	kwargs := w.Prepare()
+	w.KeyWrodParam("name", "Jerry")
+
+	result, err := kwargs.Call("first")
+	assert.Nil(t, err)
+	assert.Equal(t, "Tom", result.(map[string]interface{})["name"])
+}
+
+func TestPreparedConcurrent(t *testing.T) {
+	divide := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Return(0).ParamDefaults(0, 2).Prepare()
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := divide.CallContext(context.Background(), i*2)
+			assert.Nil(t, err)
+			assert.Equal(t, i, result)
+		}(i)
+	}
+	wg.Wait()
+
+	results, err := divide.Map(context.Background(), []int{2, 4}, pfunc.MapConcurrency(2))
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{1, 2}, results)
+
+	values, errs := divide.Batch(context.Background(), [][]interface{}{{9, 3}, {4}})
+	assert.Equal(t, []interface{}{3, 2}, values)
+	assert.Equal(t, []error{nil, nil}, errs)
+}
+
+func TestPreparedNotReady(t *testing.T) {
+	_, err := pfunc.Func("dirs/a/b/c/pfunc_test.py", "divide").Prepare().Call(6, 3)
+	assert.NotNil(t, err)
+}
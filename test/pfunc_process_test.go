@@ -0,0 +1,134 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvMap(t *testing.T) {
+	os.Setenv("PFUNC_TEST_PARENT", "parent")
+	defer os.Unsetenv("PFUNC_TEST_PARENT")
+	getEnv := pfunc.Func("dirs/a/b/c/pfunc_test.py", "get_env").Return("")
+
+	value, err := getEnv.With(pfunc.WithEnvMap(pfunc.EnvInherit, map[string]string{"PFUNC_TEST_CALL": "call"})).Params("PFUNC_TEST_CALL").Do()
+	assert.Nil(t, err)
+	assert.Equal(t, "call", value)
+	value, err = getEnv.Params("PFUNC_TEST_PARENT").Do()
+	assert.Nil(t, err)
+	assert.Equal(t, "parent", value)
+
+	// python launchers like pyenv shims need some variables
+	vars := map[string]string{"PFUNC_TEST_CALL": "call"}
+	for _, key := range []string{"PATH", "HOME", "PYENV_VERSION", "PYENV_ROOT"} {
+		if value, ok := os.LookupEnv(key); ok {
+			vars[key] = value
+		}
+	}
+	r := pfunc.DefaultRunner().With(pfunc.WithEnvMap(pfunc.EnvReplace, vars))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "get_env", "PFUNC_TEST_PARENT")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "null", result.JsonRepresentation)
+	assert.Contains(t, result.Process.Env, "PFUNC_TEST_CALL=call")
+	assert.NotContains(t, result.Process.Env, "PFUNC_TEST_PARENT=parent")
+
+	r = pfunc.DefaultRunner().With(pfunc.WithEnvMap(pfunc.EnvClear, map[string]string{"PFUNC_TEST_PARENT": ""}))
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "get_env", "PFUNC_TEST_PARENT")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "null", result.JsonRepresentation)
+	assert.NotContains(t, result.Process.Env, "PFUNC_TEST_PARENT=parent")
+}
+
+func TestWorkDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pfunc_workdir")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+
+	result := pfunc.DefaultRunner().With(pfunc.WithWorkDir(dir)).Call("dirs/a/b/c/pfunc_test.py", "get_cwd")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, dir, result.MustString())
+	assert.Equal(t, dir, result.Process.WorkDir)
+
+	cwd, _ := os.Getwd()
+	result = pfunc.Call("dirs/a/b/c/pfunc_test.py", "get_cwd")
+	assert.Equal(t, cwd, result.Process.WorkDir)
+}
+
+func TestSysPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pfunc_sys_path")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	first := filepath.Join(dir, "first")
+	second := filepath.Join(dir, "second")
+	for _, d := range []string{first, second} {
+		assert.Nil(t, os.Mkdir(d, 0755))
+		// a module shadowing the standard library and a module in both directories
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(d, "colorsys.py"), []byte("def where():\n    return "+pfunc.PythonStringLiteral(d)+"\n"), 0644))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(d, "pfunc_where.py"), []byte("def where():\n    return "+pfunc.PythonStringLiteral(d)+"\n"), 0644))
+	}
+
+	m := pfunc.Module("pfunc_where").With(pfunc.WithSysPathPrepend(first), pfunc.WithSysPathAppend(second))
+	assert.Equal(t, first, m.Call("where").MustString())
+	m = pfunc.Module("pfunc_where").With(pfunc.WithSysPathAppend(first), pfunc.WithSysPathPrepend(second))
+	assert.Equal(t, second, m.Call("where").MustString())
+
+	result := pfunc.Module("colorsys").With(pfunc.WithSysPathPrepend(first)).Call("where")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, first, result.MustString())
+	assert.Equal(t, []string{first}, result.Process.SysPathPrepend)
+	result = pfunc.Module("colorsys").With(pfunc.WithSysPathAppend(first)).Call("where")
+	assert.Equal(t, false, result.NoError)
+	assert.Equal(t, []string{first}, result.Process.SysPathAppend)
+
+	// scripts in entries of sys.path are imported by their module path
+	result = pfunc.DefaultRunner().With(pfunc.WithSysPathAppend(first)).Invoke(filepath.Join(first, "pfunc_where.py"), "where", nil)
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, pfunc.FindLine(result.TempScript, "from", "import"), "from pfunc_where import where")
+}
+
+func TestSysPathPool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pfunc_sys_path")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "pfunc_where.py"), []byte("def where():\n    return 'pool'\n"), 0644))
+
+	p, err := pfunc.DefaultRunner().With(pfunc.WithSysPathPrepend(dir)).NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+	result := pfunc.Module("pfunc_where").Pool(p).Call("where")
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, "pool", result.MustString())
+	assert.Equal(t, []string{dir}, result.Process.SysPathPrepend)
+}
+
+func TestIsolated(t *testing.T) {
+	pp, _ := filepath.Abs("dirs/pkgs")
+	old := os.Getenv(pfunc.PythonPath)
+	os.Setenv(pfunc.PythonPath, pp)
+	os.Setenv("PYTHONPFUNCTEST", "parent")
+	defer func() {
+		os.Setenv(pfunc.PythonPath, old)
+		os.Unsetenv("PYTHONPFUNCTEST")
+	}()
+
+	result := pfunc.Module("mypkg.metrics").Call("score", []int{1, 2, 3})
+	assert.Equal(t, true, result.NoError)
+
+	r := pfunc.DefaultRunner().With(pfunc.WithIsolated(true))
+	result = r.Module("mypkg.metrics").Call("score", []int{1, 2, 3})
+	assert.Equal(t, false, result.NoError)
+	assert.Equal(t, true, result.Process.Isolated)
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "get_env", "PYTHONPFUNCTEST")
+	assert.Equal(t, "null", result.JsonRepresentation)
+
+	r = r.With(pfunc.WithPythonPaths(pp), pfunc.WithEnvMap(pfunc.EnvInherit, map[string]string{"PYTHONPFUNCTEST": "call"}))
+	result = r.Module("mypkg.metrics").Call("score", []int{1, 2, 3})
+	assert.Equal(t, true, result.NoError)
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "get_env", "PYTHONPFUNCTEST")
+	assert.Equal(t, "call", result.MustString())
+}
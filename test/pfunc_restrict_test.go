@@ -0,0 +1,290 @@
+package test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertLimitError assert the exception is a LimitError of limit
+func assertLimitError(t *testing.T, limit string, err error) {
+	var le *pfunc.LimitError
+	if assert.True(t, errors.As(err, &le), "not a limit error: %v", err) {
+		assert.Equal(t, limit, le.Limit)
+	}
+}
+
+func skipWithoutRlimits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("rlimits are not supported on windows")
+	}
+}
+
+func skipWithoutSandbox(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is only supported on linux")
+	}
+	result := pfunc.DefaultRunner().With(pfunc.WithSandbox(&pfunc.Sandbox{ReadOnly: true})).
+		Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	if !result.NoError {
+		t.Skipf("linux namespaces are not available: %v", result.Exception)
+	}
+}
+
+func TestLimitCPUTime(t *testing.T) {
+	skipWithoutRlimits(t)
+	r := pfunc.DefaultRunner().With(pfunc.WithLimits(pfunc.Limits{CPUTime: time.Second}))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "burn_cpu")
+	assert.Equal(t, false, result.NoError)
+	assertLimitError(t, pfunc.LimitCPU, result.Exception)
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 3, result.MustInt())
+}
+
+func TestLimitAddressSpace(t *testing.T) {
+	skipWithoutRlimits(t)
+	r := pfunc.DefaultRunner().With(pfunc.WithLimits(pfunc.Limits{AddressSpace: 1 << 30}))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "allocate", 2048)
+	assert.Equal(t, false, result.NoError)
+	assertLimitError(t, pfunc.LimitMemory, result.Exception)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "MemoryError", pe.Type)
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "allocate", 16)
+	assert.Equal(t, true, result.NoError)
+}
+
+func TestLimitOpenFiles(t *testing.T) {
+	skipWithoutRlimits(t)
+	r := pfunc.DefaultRunner().With(pfunc.WithLimits(pfunc.Limits{OpenFiles: 32}))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "open_files", 100)
+	assert.Equal(t, false, result.NoError)
+	assertLimitError(t, pfunc.LimitOpenFiles, result.Exception)
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "open_files", 4)
+	assert.Equal(t, true, result.NoError)
+}
+
+func TestLimitOutputSize(t *testing.T) {
+	r := pfunc.DefaultRunner().With(pfunc.WithLimits(pfunc.Limits{OutputSize: 1000}))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "print_lots", 100000)
+	assert.Equal(t, false, result.NoError)
+	assertLimitError(t, pfunc.LimitOutput, result.Exception)
+	assert.Equal(t, 1000, len(result.Output))
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "print_lots", 10)
+	assert.Equal(t, true, result.NoError)
+}
+
+func TestLimitsDoNotUsePool(t *testing.T) {
+	r := pfunc.DefaultRunner().With(pfunc.WithLimits(pfunc.Limits{OutputSize: 1000}))
+	_, err := r.NewPool(1)
+	assert.NotNil(t, err)
+
+	p, err := pfunc.DefaultRunner().NewPool(1)
+	assert.Nil(t, err)
+	defer p.Close()
+	result := r.With(pfunc.WithPool(p)).Call("dirs/a/b/c/pfunc_test.py", "print_lots", 100000)
+	assertLimitError(t, pfunc.LimitOutput, result.Exception)
+}
+
+func TestSandboxNetwork(t *testing.T) {
+	skipWithoutSandbox(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "connect", port)
+	assert.Equal(t, true, result.NoError)
+
+	r := pfunc.DefaultRunner().With(pfunc.WithSandbox(&pfunc.Sandbox{}))
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "connect", port)
+	assert.Equal(t, false, result.NoError)
+	assertLimitError(t, pfunc.LimitNetwork, result.Exception)
+
+	r = pfunc.DefaultRunner().With(pfunc.WithSandbox(&pfunc.Sandbox{Network: true}))
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "connect", port)
+	assert.Equal(t, true, result.NoError)
+}
+
+func TestSandboxNetworkHostName(t *testing.T) {
+	skipWithoutSandbox(t)
+
+	// host names are not resolved without network, localhost is in /etc/hosts
+	r := pfunc.DefaultRunner().With(pfunc.WithSandbox(&pfunc.Sandbox{}))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "resolve", "example.com")
+	assert.Equal(t, false, result.NoError)
+	assertLimitError(t, pfunc.LimitNetwork, result.Exception)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "socket.gaierror", pe.QualifiedType())
+
+	// a failed lookup is not a limit without sandbox
+	result = pfunc.Call("dirs/a/b/c/pfunc_test.py", "resolve", "pfunc.invalid")
+	assert.Equal(t, false, result.NoError)
+	var le *pfunc.LimitError
+	assert.False(t, errors.As(result.Exception, &le))
+}
+
+func TestSandboxReadOnly(t *testing.T) {
+	skipWithoutSandbox(t)
+	dir, err := ioutil.TempDir("", "pfunc_sandbox")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	scratch := filepath.Join(dir, "scratch")
+	assert.Nil(t, os.Mkdir(scratch, 0755))
+
+	r := pfunc.DefaultRunner().With(pfunc.WithSandbox(&pfunc.Sandbox{ReadOnly: true, Writable: []string{scratch}}))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "write_file", filepath.Join(scratch, "a.txt"))
+	assert.Equal(t, true, result.NoError)
+	bs, err := ioutil.ReadFile(filepath.Join(scratch, "a.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "pfunc", string(bs))
+
+	result = r.Call("dirs/a/b/c/pfunc_test.py", "write_file", filepath.Join(dir, "b.txt"))
+	assert.Equal(t, false, result.NoError)
+	assertLimitError(t, pfunc.LimitFilesystem, result.Exception)
+	_, err = os.Stat(filepath.Join(dir, "b.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	// mounts of the sandbox do not leak to the host
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "c.txt"), []byte("pfunc"), 0644))
+}
+
+func TestSandboxUser(t *testing.T) {
+	skipWithoutSandbox(t)
+	if os.Getuid() != 0 {
+		t.Skip("sandbox uid requires root")
+	}
+	// the user of sandbox can not read the test directory, so the function is in a readable temp directory
+	dir, err := ioutil.TempDir("", "pfunc_sandbox")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.Chmod(dir, 0755))
+	script := filepath.Join(dir, "sandboxed.py")
+	assert.Nil(t, ioutil.WriteFile(script, []byte(`
+import os
+
+
+def user():
+    return [os.getuid(), os.getgid()]
+
+
+def fork():
+    pid = os.fork()
+    if pid == 0:
+        os._exit(0)
+    os.waitpid(pid, 0)
+    return pid
+`), 0644))
+
+	nobody := 65534
+	r := pfunc.DefaultRunner().With(pfunc.WithSandbox(&pfunc.Sandbox{ReadOnly: true, UID: &nobody, GID: &nobody}))
+	ids, err := r.Func(script, "user").Return([]int{}).Do()
+	if err != nil {
+		t.Skipf("python is not usable by uid %v: %v", nobody, err)
+	}
+	assert.Equal(t, []int{nobody, nobody}, ids)
+
+	result := r.Call(script, "fork")
+	assert.Equal(t, true, result.NoError)
+
+	r = r.With(pfunc.WithLimits(pfunc.Limits{NoChildProcesses: true}))
+	result = r.Call(script, "fork")
+	assert.Equal(t, false, result.NoError)
+	assertLimitError(t, pfunc.LimitProcesses, result.Exception)
+}
+
+// writeSiteScript write a script with a sitecustomize.py next to it, which records its open files limit and
+// tries to write a file to the directory
+func writeSiteScript(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "pfunc_site")
+	assert.Nil(t, err)
+	site := `
+import os
+try:
+    import resource
+    os.environ["PFUNC_SITE_NOFILE"] = str(resource.getrlimit(resource.RLIMIT_NOFILE)[0])
+except ImportError:
+    pass
+try:
+    open(os.path.join(os.path.dirname(os.path.abspath(__file__)), "escaped"), "w").close()
+except (IOError, OSError):
+    pass
+`
+	script := "import os\n\n\ndef site_nofile():\n    return os.environ.get(\"PFUNC_SITE_NOFILE\", \"\")\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "sitecustomize.py"), []byte(site), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "site_script.py"), []byte(script), 0644))
+	return dir, filepath.Join(dir, "site_script.py")
+}
+
+func TestLimitsSetBeforePythonReadsScript(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("limits are set by prlimit only on linux")
+	}
+	python, err := exec.LookPath(pfunc.GetPythonExecutable())
+	assert.Nil(t, err)
+	dir, err := ioutil.TempDir("", "pfunc_limits")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// the wrapper records its open files limit once the temp script can be read, then runs python with it
+	wrapper := filepath.Join(dir, "python")
+	assert.Nil(t, ioutil.WriteFile(wrapper, []byte(`#!/bin/sh
+cat > "$0.script"
+ulimit -n > "$0.nofile"
+exec `+python+` "$@" < "$0.script"
+`), 0755))
+
+	r := pfunc.NewRunner(pfunc.WithPythonExecutable(wrapper), pfunc.WithLimits(pfunc.Limits{OpenFiles: 32}))
+	result := r.Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	nofile, err := ioutil.ReadFile(wrapper + ".nofile")
+	assert.Nil(t, err)
+	assert.Equal(t, "32\n", string(nofile))
+}
+
+func TestLimitsApplyBeforeSite(t *testing.T) {
+	skipWithoutRlimits(t)
+	dir, script := writeSiteScript(t)
+	defer os.RemoveAll(dir)
+
+	r := pfunc.DefaultRunner().With(pfunc.WithLimits(pfunc.Limits{OpenFiles: 32}))
+	result := r.Call(script, "site_nofile")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Equal(t, "32", result.MustString())
+}
+
+func TestSandboxAppliesBeforeSite(t *testing.T) {
+	skipWithoutSandbox(t)
+	dir, script := writeSiteScript(t)
+	defer os.RemoveAll(dir)
+
+	r := pfunc.DefaultRunner().With(pfunc.WithSandbox(&pfunc.Sandbox{ReadOnly: true}))
+	result := r.Call(script, "site_nofile")
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	_, err := os.Stat(filepath.Join(dir, "escaped"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerDefaults(t *testing.T) {
+	r := pfunc.NewRunner()
+	assert.Equal(t, "python", r.PythonExecutable())
+	assert.Nil(t, r.Pool())
+
+	result := pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable())).
+		Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 3, result.MustInt())
+}
+
+func TestRunnersWithDifferentSettings(t *testing.T) {
+	executable := pfunc.WithPythonExecutable(pfunc.GetPythonExecutable())
+	r1 := pfunc.NewRunner(executable, pfunc.WithTemplateElementNamesPrefix("r1_"))
+	r2 := pfunc.NewRunner(executable, pfunc.WithReturnValueMarkers("<r2>", "</r2>"), pfunc.WithInjectVarNamePrefix("r2_"))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			result := r1.Call("dirs/a/b/c/pfunc_test.py", "add", i, 1)
+			assert.Equal(t, i+1, result.MustInt())
+			assert.Contains(t, result.TempScript, "r1_pfunc_return_start_")
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			result := r2.Call("dirs/a/b/c/pfunc_test.py", "add", i, 2)
+			assert.Equal(t, i+2, result.MustInt())
+			assert.Contains(t, result.TempScript, "<r2>")
+			assert.Contains(t, result.TempScript, "r2_payload")
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, pfunc.ReturnValueStartDefault, pfunc.GetReturnValueStart())
+}
+
+func TestRunnerWithPythonPaths(t *testing.T) {
+	pp, _ := filepath.Abs(filepath.Join("dirs", "a", "b"))
+	r := pfunc.NewRunner(pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()), pfunc.WithPythonPaths(pp))
+
+	result := r.Invoke("dirs/a/b/c/pfunc_test.py", "add", []interface{}{1, 2})
+	fmt.Println(result.Inspect())
+	assert.Equal(t, true, result.NoError)
+	assert.Contains(t, pfunc.FindLine(result.TempScript, "from", "import"), "from c.pfunc_test import add")
+	assert.Contains(t, result.PythonPath, pp)
+	assert.NotContains(t, os.Getenv(pfunc.PythonPath), pp)
+}
+
+func TestRunnerWithEnvAndWorkDir(t *testing.T) {
+	dir, _ := filepath.Abs("dirs")
+	r := pfunc.NewRunner(
+		pfunc.WithPythonExecutable(pfunc.GetPythonExecutable()),
+		pfunc.WithEnv(append(os.Environ(), "PFUNC_RUNNER_TEST=runner")),
+		pfunc.WithWorkDir(dir))
+
+	name, err := r.Func("dirs/a/b/c/pfunc_test.py", "get_env").
+		Params("PFUNC_RUNNER_TEST").
+		Return("").
+		Do()
+	assert.Nil(t, err)
+	assert.Equal(t, "runner", name)
+
+	cwd, err := r.Func("dirs/a/b/c/pfunc_test.py", "get_cwd").
+		Return("").
+		Do()
+	assert.Nil(t, err)
+	assert.Equal(t, dir, cwd)
+
+	pool, err := r.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	result := pool.Call("dirs/a/b/c/pfunc_test.py", "get_env", "PFUNC_RUNNER_TEST")
+	assert.Equal(t, "runner", result.MustString())
+
+	result = r.With(pfunc.WithPool(pool)).Call("dirs/a/b/c/pfunc_test.py", "get_cwd")
+	assert.Equal(t, dir, result.MustString())
+}
+
+func TestDefaultRunner(t *testing.T) {
+	old := pfunc.DefaultRunner()
+	defer pfunc.SetDefaultRunner(old)
+
+	pfunc.SetDefaultRunner(old.With(pfunc.WithTemplateElementNamesPrefix("default_")))
+	assert.Equal(t, "default_"+pfunc.ReturnValueStartDefault, pfunc.GetReturnValueStart())
+	assert.NotContains(t, old.Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2).TempScript, "default_")
+
+	result := pfunc.Call("dirs/a/b/c/pfunc_test.py", "add", 1, 2)
+	assert.Equal(t, 3, result.MustInt())
+	assert.Contains(t, result.TempScript, "default_pfunc_return_start_")
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvokeStream(t *testing.T) {
+	items, errs := pfunc.InvokeStream(context.Background(), "dirs/a/b/c/pfunc_test.py", "count_to", []interface{}{5})
+
+	var got []int
+	for item := range items {
+		var i int
+		assert.Nil(t, json.Unmarshal(item, &i))
+		got = append(got, i)
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, got)
+}
+
+func TestInvokeStreamOfList(t *testing.T) {
+	items, errs := pfunc.InvokeStream(context.Background(), "dirs/a/b/c/pfunc_test.py", "squares", []interface{}{3})
+
+	var got []string
+	for item := range items {
+		got = append(got, string(item))
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []string{"0", "1", "4"}, got)
+}
+
+func TestInvokeStreamException(t *testing.T) {
+	items, errs := pfunc.InvokeStream(context.Background(), "dirs/a/b/c/pfunc_test.py", "count_then_raise", []interface{}{2})
+
+	count := 0
+	for range items {
+		count++
+	}
+	assert.Equal(t, 2, count)
+
+	err := <-errs
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(err, &pe))
+	assert.Equal(t, "ValueError", pe.Type)
+	assert.Equal(t, "count exhausted", pe.Message)
+}
+
+func TestInvokeStreamNotIterable(t *testing.T) {
+	items, errs := pfunc.InvokeStream(context.Background(), "dirs/a/b/c/pfunc_test.py", "add", []interface{}{1, 2})
+	for range items {
+		t.Fatal("no item expected")
+	}
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(<-errs, &pe))
+	assert.Equal(t, "TypeError", pe.Type)
+}
+
+func TestInvokeStreamDeliversItemsImmediately(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	items, errs := pfunc.InvokeStream(ctx, "dirs/a/b/c/pfunc_test.py", "yield_then_sleep", []interface{}{30})
+
+	select {
+	case item := <-items:
+		assert.Equal(t, `"first"`, string(item))
+	case <-time.After(10 * time.Second):
+		t.Fatal("first item is not delivered before the generator ends")
+	}
+
+	start := time.Now()
+	cancel()
+	for range items {
+	}
+	assert.Equal(t, pfunc.ErrCanceled, <-errs)
+	assert.True(t, time.Since(start) < 10*time.Second)
+}
+
+func TestInvokeStreamCancelInfiniteGenerator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items, errs := pfunc.InvokeStream(ctx, "dirs/a/b/c/pfunc_test.py", "count_forever", nil)
+
+	for item := range items {
+		var i int
+		assert.Nil(t, json.Unmarshal(item, &i))
+		if i == 100 {
+			cancel()
+			break
+		}
+	}
+	for range items {
+	}
+	assert.Equal(t, pfunc.ErrCanceled, <-errs)
+}
+
+func TestInvokeStreamTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	items, errs := pfunc.InvokeStream(ctx, "dirs/a/b/c/pfunc_test.py", "count_forever", nil)
+
+	// nothing is received, python waits until the context is done
+	time.Sleep(2 * time.Second)
+	for range items {
+	}
+	assert.Equal(t, pfunc.ErrTimeout, <-errs)
+}
+
+func TestWrapStream(t *testing.T) {
+	items, errs := pfunc.Func("dirs/a/b/c/pfunc_test.py", "people_stream").
+		Return(Person{}).
+		Params([]string{"Bob", "Alice"}).
+		Stream(context.Background())
+
+	var got []Person
+	for item := range items {
+		got = append(got, item.(Person))
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []Person{{Name: "Bob", Age: 3, Hobby: nil}, {Name: "Alice", Age: 5, Hobby: nil}}, got)
+}
+
+func TestWrapStreamDecodeError(t *testing.T) {
+	items, errs := pfunc.Func("dirs/a/b/c/pfunc_test.py", "count_forever").
+		Return("").
+		Stream(context.Background())
+
+	for range items {
+		t.Fatal("no item expected")
+	}
+	assert.NotNil(t, <-errs)
+}
+
+func TestWrapStreamReturnTypeNotSet(t *testing.T) {
+	items, errs := pfunc.Func("dirs/a/b/c/pfunc_test.py", "count_to").Params(3).Stream(context.Background())
+	for range items {
+	}
+	assert.NotNil(t, <-errs)
+}
+
+func TestInvokeStreamItemsContainingMarkers(t *testing.T) {
+	values := []string{"x pfunc_return_end_ y", "a pfunc_exception_start_boom pfunc_exception_end_ b", ""}
+	items, errs := pfunc.InvokeStream(context.Background(), "dirs/a/b/c/pfunc_test.py", "echo", []interface{}{values})
+
+	var got []string
+	for item := range items {
+		var s string
+		assert.Nil(t, json.Unmarshal(item, &s))
+		got = append(got, s)
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, values, got)
+}
+
+func TestWrapStreamArgs(t *testing.T) {
+	items, errs := pfunc.Func("dirs/a/b/c/pfunc_test.py", "count_to").
+		Return(0).
+		Params(5).
+		Stream(context.Background(), 2)
+
+	var got []int
+	for item := range items {
+		got = append(got, item.(int))
+	}
+	assert.Nil(t, <-errs)
+	assert.Equal(t, []int{0, 1}, got)
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+type ClassPerson struct {
+	_        struct{} `pfunc:"class=pfunc_records.Person"`
+	Name     string   `json:"name"`
+	Age      int      `json:"age"`
+	FullName string   `pfunc:"full_name"`
+}
+
+type ClassTeam struct {
+	_       struct{}      `pfunc:"class=pfunc_records.Team"`
+	Leader  ClassPerson   `json:"leader"`
+	Members []ClassPerson `json:"members"`
+}
+
+type ClassCard struct {
+	_     struct{} `pfunc:"class=pfunc_records.Card"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+}
+
+type ClassMissing struct {
+	_    struct{} `pfunc:"class=pfunc_records.Missing"`
+	Name string
+}
+
+var ann = ClassPerson{Name: "Ann", Age: 30, FullName: "Ann Lee"}
+
+func TestStructAsPythonObject(t *testing.T) {
+	var described []interface{}
+	result := pfunc.Call("dirs/a/b/c/pfunc_records.py", "describe_person", ann)
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Nil(t, result.Decode(&described))
+	assert.Equal(t, []interface{}{"Person", "hi Ann", 30.0, "Ann Lee"}, described)
+
+	team := ClassTeam{Leader: ann, Members: []ClassPerson{{Name: "Bob", Age: 20}}}
+	result = pfunc.Call("dirs/a/b/c/pfunc_records.py", "describe_team", team)
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Nil(t, result.Decode(&described))
+	assert.Equal(t, []interface{}{"Team", "hi Ann", []interface{}{"Person Bob"}}, described)
+
+	// returned objects decode into the struct with renamed fields
+	older, err := pfunc.Func("dirs/a/b/c/pfunc_records.py", "older").Return(ClassPerson{}).Params(ann).KeyWrodParam("years", 2).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, ClassPerson{Name: "Ann", Age: 32, FullName: "Ann Lee"}, older)
+}
+
+func TestStructAsPythonObjectInPoolAndBatch(t *testing.T) {
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+
+	older, err := pfunc.Func("dirs/a/b/c/pfunc_records.py", "older").Pool(pool).Return(ClassPerson{}).Params(ann, 1).Do()
+	assert.Nil(t, err)
+	assert.Equal(t, 31, older.(ClassPerson).Age)
+
+	// the worker survives a class which can not be imported
+	result := pool.Call("dirs/a/b/c/pfunc_records.py", "describe_person", ClassMissing{Name: "x"})
+	assert.Equal(t, false, result.NoError)
+	var pe *pfunc.PythonError
+	assert.True(t, errors.As(result.Exception, &pe))
+	assert.Equal(t, "AttributeError", pe.Type)
+	result = pool.Call("dirs/a/b/c/pfunc_records.py", "describe_person", ann)
+	assert.Equal(t, true, result.NoError, result.Inspect())
+
+	results := pfunc.InvokeBatch("dirs/a/b/c/pfunc_records.py", "older", [][]interface{}{{ann, 1}, {ann, 2}})
+	for i, result := range results {
+		var p ClassPerson
+		assert.Nil(t, result.Decode(&p))
+		assert.Equal(t, 31+i, p.Age)
+	}
+
+}
+
+func TestStructAsDataclass(t *testing.T) {
+	skipPython2(t)
+
+	var described []interface{}
+	result := pfunc.Call("dirs/a/b/c/pfunc_records.py", "describe_card", ClassCard{Title: "todo", Tags: []string{"a"}})
+	assert.Equal(t, true, result.NoError, result.Inspect())
+	assert.Nil(t, result.Decode(&described))
+	assert.Equal(t, []interface{}{"Card", true, "todo", []interface{}{"a"}}, described)
+}
+
+func TestMarshalStructClass(t *testing.T) {
+	bs, err := pfunc.Marshal(ann)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"__pfunc__":"object","value":{"class":"pfunc_records.Person","fields":{"age":30,"full_name":"Ann Lee","name":"Ann"}}}`, string(bs))
+
+	var p ClassPerson
+	assert.Nil(t, pfunc.Unmarshal(bs, &p))
+	assert.Equal(t, ann, p)
+
+	// renamed fields are decoded from plain json too
+	assert.Nil(t, pfunc.Unmarshal([]byte(`{"name": "Bob", "full_name": "Bob Ray"}`), &p))
+	assert.Equal(t, "Bob Ray", p.FullName)
+}
+
+func TestMapWithTagKeyIsNotAnObject(t *testing.T) {
+	marker := filepath.Join(os.TempDir(), "pfunc_injected_marker")
+	os.Remove(marker)
+	m := map[string]interface{}{
+		"__pfunc__": "object",
+		"value": map[string]interface{}{
+			"class":  "subprocess.check_output",
+			"fields": map[string]interface{}{"args": []interface{}{"touch", marker}},
+		},
+	}
+
+	pool, err := pfunc.NewPool(1)
+	assert.Nil(t, err)
+	defer pool.Close()
+	results := []pfunc.PResult{
+		pfunc.Call("dirs/a/b/c/pfunc_test.py", "echo", m),
+		pool.Call("dirs/a/b/c/pfunc_test.py", "echo", m),
+		pfunc.InvokeBatch("dirs/a/b/c/pfunc_test.py", "echo", [][]interface{}{{m}})[0],
+	}
+	for _, result := range results {
+		assert.Equal(t, true, result.NoError, result.Inspect())
+		var echoed map[string]interface{}
+		assert.Nil(t, result.Decode(&echoed))
+		assert.Equal(t, m, echoed)
+	}
+	_, err = os.Stat(marker)
+	assert.True(t, os.IsNotExist(err))
+
+	bs, err := pfunc.Marshal(m)
+	assert.Nil(t, err)
+	var v map[string]interface{}
+	assert.Nil(t, pfunc.Unmarshal(bs, &v))
+	assert.Equal(t, m, v)
+}
+
+func TestObjectTagOnlyForClassesOfStructs(t *testing.T) {
+	// raw json is not looked at by Marshal, its tagged objects do not name classes python may make
+	raw := json.RawMessage(`{"__pfunc__": "object", "value": {"class": "pfunc_records.Person", "fields": {"name": "Ann", "age": 30}}}`)
+	result := pfunc.Call("dirs/a/b/c/pfunc_records.py", "describe_person", raw)
+	assert.Equal(t, false, result.NoError)
+	assert.Contains(t, result.Exception.Error(), "is not a class of go structs sent to python")
+
+	r := pfunc.NewRunner()
+	assert.Nil(t, r.RegisterCallback("person", func() ClassPerson { return ann }))
+	var p ClassPerson
+	assert.Nil(t, r.Call("dirs/a/b/c/pfunc_test.py", "call_callback", "person").Decode(&p))
+	assert.Equal(t, ann, p)
+}
@@ -0,0 +1,240 @@
+// Code generated by pfuncgen from pfunc_typed.py. DO NOT EDIT.
+
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/gitpillow/pfunc"
+)
+
+// PfuncTypedScript is the path of python script pfunc_typed.py used at runtime
+var PfuncTypedScript = "dirs/a/b/c/pfunc_typed.py"
+
+// PfuncTypedRunner invokes python functions of pfunc_typed.py, nil means the default runner
+var PfuncTypedRunner *pfunc.Runner
+
+// Point is python TypedDict Point.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Employee is python dataclass Employee.
+//
+// An employee of a company.
+type Employee struct {
+	Name    string   `json:"name"`
+	Age     int      `json:"age"`
+	Skills  []string `json:"skills"`
+	Manager *string  `json:"manager,omitempty"`
+	Active  *bool    `json:"active,omitempty"`
+	Level   *int     `json:"level,omitempty"`
+}
+
+// Distance invokes python function distance(a, b).
+//
+// Euclidean distance between two points.
+func Distance(ctx context.Context, a Point, b Point) (float64, error) {
+	var result float64
+	params := []interface{}{a, b}
+	kwargs := map[string]interface{}{}
+
+	err := pfuncTypedCall(ctx, "distance", &result, params, kwargs)
+	return result, err
+}
+
+// Middle invokes python function middle(a, b).
+func Middle(ctx context.Context, a Point, b Point) (Point, error) {
+	var result Point
+	params := []interface{}{a, b}
+	kwargs := map[string]interface{}{}
+
+	err := pfuncTypedCall(ctx, "middle", &result, params, kwargs)
+	return result, err
+}
+
+// GreetOptions holds optional params of python function greet, nil fields are not passed.
+type GreetOptions struct {
+	// Greeting defaults to "Hello" in python
+	Greeting *string
+	// Punctuation defaults to "!" in python
+	Punctuation *string
+}
+
+// Greet invokes python function greet(name, greeting="Hello", punctuation="!").
+//
+// Greet someone.
+//
+// The greeting and punctuation can be changed.
+func Greet(ctx context.Context, name string, options *GreetOptions) (string, error) {
+	var result string
+	params := []interface{}{name}
+	kwargs := map[string]interface{}{}
+	if options != nil {
+		if options.Greeting != nil {
+			kwargs["greeting"] = *options.Greeting
+		}
+		if options.Punctuation != nil {
+			kwargs["punctuation"] = *options.Punctuation
+		}
+	}
+
+	err := pfuncTypedCall(ctx, "greet", &result, params, kwargs)
+	return result, err
+}
+
+// Total invokes python function total(*values).
+func Total(ctx context.Context, values ...int) (int, error) {
+	var result int
+	params := []interface{}{}
+	for _, v := range values {
+		params = append(params, v)
+	}
+	kwargs := map[string]interface{}{}
+
+	err := pfuncTypedCall(ctx, "total", &result, params, kwargs)
+	return result, err
+}
+
+// Labels invokes python function labels(prefix, separator, **pairs).
+func Labels(ctx context.Context, prefix string, separator string, pairs map[string]interface{}) ([]string, error) {
+	var result []string
+	params := []interface{}{prefix}
+	kwargs := map[string]interface{}{
+		"separator": separator,
+	}
+	for k, v := range pairs {
+		kwargs[k] = v
+	}
+
+	err := pfuncTypedCall(ctx, "labels", &result, params, kwargs)
+	return result, err
+}
+
+// Find invokes python function find(scores, key).
+func Find(ctx context.Context, scores map[string]int, key string) (*int, error) {
+	var result *int
+	params := []interface{}{scores, key}
+	kwargs := map[string]interface{}{}
+
+	err := pfuncTypedCall(ctx, "find", &result, params, kwargs)
+	return result, err
+}
+
+// Bounds invokes python function bounds(values).
+func Bounds(ctx context.Context, values []float64) ([]float64, error) {
+	var result []float64
+	params := []interface{}{values}
+	kwargs := map[string]interface{}{}
+
+	err := pfuncTypedCall(ctx, "bounds", &result, params, kwargs)
+	return result, err
+}
+
+// UntypedOptions holds optional params of python function untyped, nil fields are not passed.
+type UntypedOptions struct {
+	// B defaults to 2 in python
+	B *interface{}
+}
+
+// Untyped invokes python function untyped(a, b=2).
+func Untyped(ctx context.Context, a interface{}, options *UntypedOptions) (interface{}, error) {
+	var result interface{}
+	params := []interface{}{a}
+	kwargs := map[string]interface{}{}
+	if options != nil {
+		if options.B != nil {
+			kwargs["b"] = *options.B
+		}
+	}
+
+	err := pfuncTypedCall(ctx, "untyped", &result, params, kwargs)
+	return result, err
+}
+
+// Hire invokes python function hire(name, age).
+func Hire(ctx context.Context, name string, age int) (Employee, error) {
+	var result Employee
+	params := []interface{}{name, age}
+	kwargs := map[string]interface{}{}
+
+	err := pfuncTypedCall(ctx, "hire", &result, params, kwargs)
+	return result, err
+}
+
+// Promote invokes python function promote(employee).
+func Promote(ctx context.Context, employee Employee) (Employee, error) {
+	var result Employee
+	params := []interface{}{employee}
+	kwargs := map[string]interface{}{}
+
+	err := pfuncTypedCall(ctx, "promote", &result, params, kwargs)
+	return result, err
+}
+
+// Nothing invokes python function nothing(type).
+func Nothing(ctx context.Context, typeParam string) error {
+	params := []interface{}{typeParam}
+	kwargs := map[string]interface{}{}
+
+	return pfuncTypedCall(ctx, "nothing", nil, params, kwargs)
+}
+
+// ClampOptions holds optional params of python function clamp, nil fields are not passed.
+type ClampOptions struct {
+	// Low defaults to 0 in python
+	Low *int
+	// High defaults to 10 in python
+	High *int
+}
+
+// Clamp invokes python function clamp(value, low=0, high=10, /).
+func Clamp(ctx context.Context, value int, options *ClampOptions) (int, error) {
+	var result int
+	params := []interface{}{value}
+	kwargs := map[string]interface{}{}
+	if options != nil {
+		if options.Low != nil {
+			params = append(params, *options.Low)
+		}
+		if options.High != nil {
+			if len(params) != 2 {
+				return result, errors.New("ClampOptions.Low must be set with High, positional only params are passed in order")
+			}
+			params = append(params, *options.High)
+		}
+	}
+
+	err := pfuncTypedCall(ctx, "clamp", &result, params, kwargs)
+	return result, err
+}
+
+// Größe invokes python function größe(breite, höhe).
+func Größe(ctx context.Context, breite int, höhe int) (int, error) {
+	var result int
+	params := []interface{}{breite, höhe}
+	kwargs := map[string]interface{}{}
+
+	err := pfuncTypedCall(ctx, "größe", &result, params, kwargs)
+	return result, err
+}
+
+// pfuncTypedCall invoke python function and decode its return value into result
+func pfuncTypedCall(ctx context.Context, funcName string, result interface{}, params []interface{}, kwargs map[string]interface{}) error {
+	w := pfunc.Func(PfuncTypedScript, funcName)
+	if PfuncTypedRunner != nil {
+		w = PfuncTypedRunner.Func(PfuncTypedScript, funcName)
+	}
+	w.Params(params...)
+	for k, v := range kwargs {
+		w.KeyWrodParam(k, v)
+	}
+	raw, err := w.Return(json.RawMessage{}).DoContext(ctx)
+	if err != nil || result == nil {
+		return err
+	}
+	return pfunc.Unmarshal(raw.(json.RawMessage), result)
+}
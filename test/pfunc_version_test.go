@@ -0,0 +1,35 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gitpillow/pfunc"
+	"github.com/stretchr/testify/assert"
+)
+
+// run whole test suite with the python executable in PFUNC_PYTHON, if it is set
+func TestMain(m *testing.M) {
+	if executable := os.Getenv("PFUNC_PYTHON"); len(executable) > 0 {
+		pfunc.SetPythonExecutable(executable)
+	}
+	os.Exit(m.Run())
+}
+
+func TestProbePythonVersion(t *testing.T) {
+	v, err := pfunc.ProbePythonVersion(pfunc.GetPythonExecutable())
+	assert.Nil(t, err)
+	assert.Contains(t, []int{pfunc.Python2, pfunc.Python3}, v)
+
+	_, err = pfunc.ProbePythonVersion("python_not_exists")
+	assert.NotNil(t, err)
+}
+
+func TestScriptTemplateRunsOnBothVersions(t *testing.T) {
+	// the suite runs with python 2 and python 3, both run the same temp script
+	result := pfunc.Invoke("dirs/a/b/c/pfunc_test.py", "add", []interface{}{1, 2})
+	assert.Equal(t, true, result.NoError)
+	assert.Equal(t, 3, result.MustInt())
+	assert.Contains(t, result.TempScript, "except Exception as e:")
+	assert.Equal(t, pfunc.PythonScriptTemplate, pfunc.Python2ScriptTemplate)
+}
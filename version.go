@@ -0,0 +1,21 @@
+package pfunc
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ProbePythonVersion run the python executable to get its major version
+func ProbePythonVersion(executable string) (int, error) {
+	bs, err := exec.Command(executable, "-c", "import sys; sys.stdout.write(str(sys.version_info[0]))").Output()
+	if err != nil {
+		return 0, fmt.Errorf("probe python version error: %v: %v", executable, err)
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(string(bs)))
+	if err != nil {
+		return 0, fmt.Errorf("probe python version error: unexpected output %q: %v", string(bs), err)
+	}
+	return v, nil
+}